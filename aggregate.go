@@ -0,0 +1,119 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// aggNode holds the spans at the same path of service and operation names
+// across many traces.
+type aggNode struct {
+	service   string
+	operation string
+	durations []int64
+	errors    int
+	children  []*aggNode
+	byKey     map[[2]string]*aggNode
+}
+
+func (a *aggNode) child(n *spanNode) *aggNode {
+	key := [2]string{n.service, n.span.OperationName}
+	if c, ok := a.byKey[key]; ok {
+		return c
+	}
+	c := &aggNode{service: n.service, operation: n.span.OperationName}
+	if a.byKey == nil {
+		a.byKey = make(map[[2]string]*aggNode)
+	}
+	a.byKey[key] = c
+	a.children = append(a.children, c)
+	return c
+}
+
+func (a *aggNode) add(nodes []*spanNode) {
+	for _, n := range nodes {
+		c := a.child(n)
+		c.durations = append(c.durations, n.span.Duration)
+		if n.hasError() {
+			c.errors++
+		}
+		c.add(n.children)
+	}
+}
+
+// sortDurations sorts the durations throughout the tree for percentiles.
+func (a *aggNode) sortDurations() {
+	sort.Slice(a.durations, func(i, j int) bool { return a.durations[i] < a.durations[j] })
+	for _, c := range a.children {
+		c.sortDurations()
+	}
+}
+
+func (a *aggNode) errorRate() float64 {
+	if len(a.durations) == 0 {
+		return 0
+	}
+	return float64(a.errors) / float64(len(a.durations))
+}
+
+// aggregateTrees merges the trees of several traces, returning a root whose
+// children are the aggregated trace roots.
+func aggregateTrees(trees [][]*spanNode) *aggNode {
+	root := &aggNode{}
+	for _, roots := range trees {
+		root.add(roots)
+	}
+	root.sortDurations()
+	return root
+}
+
+func printAggregate(w io.Writer, nodes []*aggNode, depth int, traces int) {
+	for _, a := range nodes {
+		d := a.durations
+		fmt.Fprintf(w, "%s%s [%s] count %d", strings.Repeat("  ", depth), a.operation, a.service, len(d))
+		if traces > 1 {
+			fmt.Fprintf(w, " (%.1f/trace)", float64(len(d))/float64(traces))
+		}
+		fmt.Fprintf(w, " p50 %s p90 %s p99 %s max %s",
+			formatDuration(percentile(d, 50)),
+			formatDuration(percentile(d, 90)),
+			formatDuration(percentile(d, 99)),
+			formatDuration(d[len(d)-1]),
+		)
+		if a.errors > 0 {
+			fmt.Fprintf(w, " errors %.1f%%", a.errorRate()*100)
+		}
+		fmt.Fprintln(w)
+		printAggregate(w, a.children, depth+1, traces)
+	}
+}
+
+func runAggregate(args []string) {
+	var src traceSource
+	fs := flag.NewFlagSet("aggregate", flag.ExitOnError)
+	src.register(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `jtree aggregate - characterise many traces as one tree
+
+Usage:
+  jtree aggregate [flags] <trace-id>...
+  jtree aggregate [flags] -          (read trace IDs from stdin)
+  jtree aggregate -service <service> [-operation <op>] [flags]
+
+Spans are grouped by their path of service and operation names. Each line
+shows the span count, duration percentiles and error rate.
+
+Flags:
+`)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	trees := loadTrees(&src, fs)
+	fmt.Printf("%d traces\n", len(trees))
+	printAggregate(os.Stdout, aggregateTrees(trees).children, 0, len(trees))
+}
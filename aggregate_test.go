@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func aggregateFixture() [][]*spanNode {
+	build := func(rootDuration int64, queries []int64, errIndex int) []*spanNode {
+		spans := []span{
+			{SpanID: "root", OperationName: "GET /orders", StartTime: 1000, Duration: rootDuration, ProcessID: "p1"},
+		}
+		for i, d := range queries {
+			s := span{
+				SpanID:        string(rune('a' + i)),
+				OperationName: "db.query",
+				References:    []reference{{RefType: "CHILD_OF", SpanID: "root"}},
+				StartTime:     int64(1100 + i*100),
+				Duration:      d,
+				ProcessID:     "p2",
+			}
+			if i == errIndex {
+				s.Tags = []tag{{Key: "error", Value: true}}
+			}
+			spans = append(spans, s)
+		}
+		roots, _ := buildTree(trace{
+			Spans: spans,
+			Processes: map[string]process{
+				"p1": {ServiceName: "api"},
+				"p2": {ServiceName: "postgres"},
+			},
+		})
+		return roots
+	}
+
+	return [][]*spanNode{
+		build(10_000, []int64{1_000, 2_000}, -1),
+		build(20_000, []int64{3_000}, 0),
+		build(30_000, []int64{4_000, 5_000, 6_000}, -1),
+	}
+}
+
+func TestAggregateTrees(t *testing.T) {
+	root := aggregateTrees(aggregateFixture())
+
+	if len(root.children) != 1 {
+		t.Fatalf("expected 1 aggregated root, got %d", len(root.children))
+	}
+	req := root.children[0]
+	if req.operation != "GET /orders" || len(req.durations) != 3 {
+		t.Errorf("unexpected root: %s with %d spans", req.operation, len(req.durations))
+	}
+
+	if len(req.children) != 1 {
+		t.Fatalf("expected 1 aggregated child, got %d", len(req.children))
+	}
+	q := req.children[0]
+	if len(q.durations) != 6 {
+		t.Errorf("expected 6 db.query spans, got %d", len(q.durations))
+	}
+	for i := 1; i < len(q.durations); i++ {
+		if q.durations[i] < q.durations[i-1] {
+			t.Errorf("durations not sorted: %v", q.durations)
+		}
+	}
+	if q.errors != 1 {
+		t.Errorf("expected 1 error, got %d", q.errors)
+	}
+}
+
+func TestPrintAggregate(t *testing.T) {
+	root := aggregateTrees(aggregateFixture())
+
+	var buf bytes.Buffer
+	printAggregate(&buf, root.children, 0, 3)
+
+	want := `GET /orders [api] count 3 (1.0/trace) p50 20.00ms p90 30.00ms p99 30.00ms max 30.00ms
+  db.query [postgres] count 6 (2.0/trace) p50 3.00ms p90 6.00ms p99 6.00ms max 6.00ms errors 16.7%
+`
+	if got := buf.String(); got != want {
+		t.Errorf("printAggregate() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// baseline is an aggregated profile of span durations, keyed by the path of
+// service and operation names, stored to compare later traces against.
+type baseline struct {
+	Traces int             `json:"traces"`
+	Spans  []baselineEntry `json:"spans"`
+}
+
+// baselineEntry holds the duration percentiles, in microseconds, of the spans
+// at one path.
+type baselineEntry struct {
+	Path  []string `json:"path"`
+	Count int      `json:"count"`
+	P50   int64    `json:"p50_us"`
+	P90   int64    `json:"p90_us"`
+	P99   int64    `json:"p99_us"`
+	Max   int64    `json:"max_us"`
+}
+
+func (e baselineEntry) percentile(p string) int64 {
+	switch p {
+	case "p50":
+		return e.P50
+	case "p99":
+		return e.P99
+	case "max":
+		return e.Max
+	default:
+		return e.P90
+	}
+}
+
+type regression struct {
+	path     []string
+	current  int64
+	baseline int64
+}
+
+// flatten returns an entry for every node in the aggregated tree, parents
+// before children.
+func (a *aggNode) flatten(path []string) []baselineEntry {
+	var entries []baselineEntry
+	for _, c := range a.children {
+		p := append(append([]string(nil), path...), fmt.Sprintf("%s [%s]", c.operation, c.service))
+		d := c.durations
+		entries = append(entries, baselineEntry{
+			Path:  p,
+			Count: len(d),
+			P50:   percentile(d, 50),
+			P90:   percentile(d, 90),
+			P99:   percentile(d, 99),
+			Max:   d[len(d)-1],
+		})
+		entries = append(entries, c.flatten(p)...)
+	}
+	return entries
+}
+
+func newBaseline(trees [][]*spanNode) baseline {
+	return baseline{
+		Traces: len(trees),
+		Spans:  aggregateTrees(trees).flatten(nil),
+	}
+}
+
+// compare returns the paths whose chosen percentile exceeds the baseline by
+// more than factor and is at least minDuration microseconds. Paths missing
+// from the baseline are returned separately.
+func (b baseline) compare(
+	current baseline,
+	p string,
+	factor float64,
+	minDuration int64,
+) (regressions []regression, unknown [][]string) {
+	known := make(map[string]baselineEntry, len(b.Spans))
+	for _, e := range b.Spans {
+		known[strings.Join(e.Path, "\x00")] = e
+	}
+
+	for _, e := range current.Spans {
+		base, ok := known[strings.Join(e.Path, "\x00")]
+		if !ok {
+			unknown = append(unknown, e.Path)
+			continue
+		}
+		cur, limit := e.percentile(p), base.percentile(p)
+		if cur >= minDuration && float64(cur) > float64(limit)*factor {
+			regressions = append(regressions, regression{path: e.Path, current: cur, baseline: limit})
+		}
+	}
+	return regressions, unknown
+}
+
+func printRegressions(w io.Writer, regressions []regression, unknown [][]string, p string, factor float64) {
+	for _, r := range regressions {
+		fmt.Fprintf(w, "REGRESSION %s\n  %s %s > %.1f × baseline %s\n",
+			strings.Join(r.path, " > "),
+			p,
+			formatDuration(r.current),
+			factor,
+			formatDuration(r.baseline),
+		)
+	}
+	for _, path := range unknown {
+		fmt.Fprintf(w, "NEW %s\n", strings.Join(path, " > "))
+	}
+	if len(regressions) == 0 {
+		fmt.Fprintln(w, "no regressions")
+	}
+}
+
+func runBaseline(args []string) {
+	usage := func() {
+		fmt.Fprintf(os.Stderr, `jtree baseline - record and check latency baselines
+
+Usage:
+  jtree baseline save -o <file> [flags] [trace-id...]
+  jtree baseline check -baseline <file> [flags] [trace-id...]
+
+Traces are given as for jtree aggregate: IDs, - to read IDs from stdin, or
+-service to search Jaeger. check exits with status 1 if any span path
+exceeds the baseline.
+`)
+	}
+	if len(args) < 1 {
+		usage()
+		os.Exit(1)
+	}
+
+	var src traceSource
+	fs := flag.NewFlagSet("baseline "+args[0], flag.ExitOnError)
+	src.register(fs)
+
+	switch args[0] {
+	case "save":
+		out := fs.String("o", "", "file to write the baseline to")
+		fs.Parse(args[1:])
+		if *out == "" {
+			fmt.Fprintln(os.Stderr, "missing -o file")
+			os.Exit(1)
+		}
+
+		b := newBaseline(loadTrees(&src, fs))
+		data, err := json.MarshalIndent(b, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to encode baseline: %v\n", err)
+			os.Exit(1)
+		}
+		if err := os.WriteFile(*out, append(data, '\n'), 0o644); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to write baseline: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("saved baseline of %d span paths from %d traces to %s\n", len(b.Spans), b.Traces, *out)
+
+	case "check":
+		file := fs.String("baseline", "", "baseline file written by jtree baseline save")
+		factor := fs.Float64("factor", 1.5, "flag spans slower than this multiple of the baseline")
+		p := fs.String("percentile", "p90", "percentile to compare: p50, p90, p99 or max")
+		minDuration := fs.Duration("min-duration", 0, "ignore spans faster than this value")
+		fs.Parse(args[1:])
+		if *file == "" {
+			fmt.Fprintln(os.Stderr, "missing -baseline file")
+			os.Exit(1)
+		}
+		switch *p {
+		case "p50", "p90", "p99", "max":
+		default:
+			fmt.Fprintf(os.Stderr, "invalid -percentile %q: must be p50, p90, p99 or max\n", *p)
+			os.Exit(1)
+		}
+
+		data, err := os.ReadFile(*file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to read baseline: %v\n", err)
+			os.Exit(1)
+		}
+		var base baseline
+		if err := json.Unmarshal(data, &base); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to decode baseline: %v\n", err)
+			os.Exit(1)
+		}
+
+		current := newBaseline(loadTrees(&src, fs))
+		regressions, unknown := base.compare(current, *p, *factor, minDuration.Microseconds())
+		printRegressions(os.Stdout, regressions, unknown, *p, *factor)
+		if len(regressions) > 0 {
+			os.Exit(1)
+		}
+
+	default:
+		usage()
+		os.Exit(1)
+	}
+}
+
+// loadTrees loads the traces named by the parsed flag set's arguments and
+// builds their trees, exiting on failure.
+func loadTrees(src *traceSource, fs *flag.FlagSet) [][]*spanNode {
+	traces, err := src.load(fs.Args(), os.Stdin)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	trees := make([][]*spanNode, len(traces))
+	for i, t := range traces {
+		trees[i], _ = buildTree(t)
+	}
+	return trees
+}
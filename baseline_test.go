@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestNewBaseline(t *testing.T) {
+	b := newBaseline(aggregateFixture())
+
+	if b.Traces != 3 {
+		t.Errorf("Traces = %d, want 3", b.Traces)
+	}
+	if len(b.Spans) != 2 {
+		t.Fatalf("expected 2 span paths, got %d", len(b.Spans))
+	}
+
+	root := b.Spans[0]
+	if len(root.Path) != 1 || root.Path[0] != "GET /orders [api]" {
+		t.Errorf("unexpected root path %v", root.Path)
+	}
+	if root.Count != 3 || root.P50 != 20_000 || root.Max != 30_000 {
+		t.Errorf("unexpected root entry %+v", root)
+	}
+
+	q := b.Spans[1]
+	if len(q.Path) != 2 || q.Path[1] != "db.query [postgres]" {
+		t.Errorf("unexpected query path %v", q.Path)
+	}
+	if q.Count != 6 || q.P50 != 3_000 || q.P90 != 6_000 {
+		t.Errorf("unexpected query entry %+v", q)
+	}
+}
+
+func TestBaseline_compare(t *testing.T) {
+	base := baseline{Spans: []baselineEntry{
+		{Path: []string{"root [api]"}, P50: 10_000, P90: 20_000},
+		{Path: []string{"root [api]", "db.query [postgres]"}, P50: 1_000, P90: 2_000},
+		{Path: []string{"root [api]", "cache.get [redis]"}, P50: 10, P90: 20},
+	}}
+	current := baseline{Spans: []baselineEntry{
+		{Path: []string{"root [api]"}, P50: 12_000, P90: 29_000},
+		{Path: []string{"root [api]", "db.query [postgres]"}, P50: 1_000, P90: 5_000},
+		{Path: []string{"root [api]", "cache.get [redis]"}, P50: 100, P90: 200},
+		{Path: []string{"root [api]", "auth [api]"}, P50: 500, P90: 600},
+	}}
+
+	regressions, unknown := base.compare(current, "p90", 1.5, 0)
+	if len(regressions) != 2 {
+		t.Fatalf("expected 2 regressions, got %d", len(regressions))
+	}
+	if regressions[0].path[1] != "db.query [postgres]" || regressions[0].current != 5_000 || regressions[0].baseline != 2_000 {
+		t.Errorf("unexpected regression %+v", regressions[0])
+	}
+	if len(unknown) != 1 || unknown[0][1] != "auth [api]" {
+		t.Errorf("expected auth to be unknown, got %v", unknown)
+	}
+
+	regressions, _ = base.compare(current, "p90", 1.5, 1_000)
+	if len(regressions) != 1 {
+		t.Errorf("expected min duration to drop the cache regression, got %d", len(regressions))
+	}
+
+	regressions, _ = base.compare(current, "p50", 1.5, 0)
+	if len(regressions) != 1 || regressions[0].path[1] != "cache.get [redis]" {
+		t.Errorf("expected only the cache regression at p50, got %+v", regressions)
+	}
+}
+
+func TestPrintRegressions(t *testing.T) {
+	var buf bytes.Buffer
+	printRegressions(&buf, []regression{
+		{path: []string{"root [api]", "db.query [postgres]"}, current: 5_000, baseline: 2_000},
+	}, [][]string{{"root [api]", "auth [api]"}}, "p90", 1.5)
+
+	want := `REGRESSION root [api] > db.query [postgres]
+  p90 5.00ms > 1.5 × baseline 2.00ms
+NEW root [api] > auth [api]
+`
+	if got := buf.String(); got != want {
+		t.Errorf("printRegressions() =\n%s\nwant\n%s", got, want)
+	}
+
+	buf.Reset()
+	printRegressions(&buf, nil, nil, "p90", 1.5)
+	if got := buf.String(); got != "no regressions\n" {
+		t.Errorf("printRegressions() = %q, want %q", got, "no regressions\n")
+	}
+}
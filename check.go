@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+type ruleSet struct {
+	Rules []rule `yaml:"rules"`
+}
+
+// rule is an assertion about the spans selected by service and operation.
+// With neither set, every span in the trace is selected.
+type rule struct {
+	Name        string        `yaml:"name"`
+	Service     string        `yaml:"service"`
+	Operation   string        `yaml:"operation"`
+	NoErrors    bool          `yaml:"no_errors"`
+	MaxDuration time.Duration `yaml:"max_duration"`
+	MinCount    *int          `yaml:"min_count"`
+	MaxCount    *int          `yaml:"max_count"`
+	Required    bool          `yaml:"required"`
+}
+
+type ruleResult struct {
+	Rule       string   `json:"rule"`
+	Passed     bool     `json:"passed"`
+	Violations []string `json:"violations,omitempty"`
+}
+
+type checkReport struct {
+	TraceID string       `json:"trace_id"`
+	Passed  bool         `json:"passed"`
+	Results []ruleResult `json:"results"`
+}
+
+func parseRules(data []byte) (ruleSet, error) {
+	var rs ruleSet
+	// Reject unknown keys so that a misspelt assertion fails the check
+	// instead of being skipped.
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&rs); err != nil && !errors.Is(err, io.EOF) {
+		return ruleSet{}, fmt.Errorf("failed to parse rules: %w", err)
+	}
+	if len(rs.Rules) == 0 {
+		return ruleSet{}, fmt.Errorf("no rules defined")
+	}
+	for i, r := range rs.Rules {
+		if !r.NoErrors && r.MaxDuration == 0 && r.MinCount == nil && r.MaxCount == nil && !r.Required {
+			return ruleSet{}, fmt.Errorf("rule %d (%s) has no assertion", i+1, r.describe())
+		}
+	}
+	return rs, nil
+}
+
+// describe returns the rule's name, or a summary of it if it has none.
+func (r rule) describe() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	var parts []string
+	if r.Service != "" {
+		parts = append(parts, "service "+r.Service)
+	}
+	if r.Operation != "" {
+		parts = append(parts, "operation "+r.Operation)
+	}
+	if len(parts) == 0 {
+		parts = append(parts, "all spans")
+	}
+	if r.Required {
+		parts = append(parts, "required")
+	}
+	if r.NoErrors {
+		parts = append(parts, "no errors")
+	}
+	if r.MaxDuration > 0 {
+		parts = append(parts, "< "+r.MaxDuration.String())
+	}
+	if r.MinCount != nil {
+		parts = append(parts, fmt.Sprintf("at least %d calls", *r.MinCount))
+	}
+	if r.MaxCount != nil {
+		parts = append(parts, fmt.Sprintf("at most %d calls", *r.MaxCount))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (r rule) selects(n *spanNode) bool {
+	return (r.Service == "" || n.service == r.Service) &&
+		(r.Operation == "" || n.span.OperationName == r.Operation)
+}
+
+func (r rule) check(roots []*spanNode, startTime int64) ruleResult {
+	var selected []*spanNode
+	var walk func(nodes []*spanNode)
+	walk = func(nodes []*spanNode) {
+		for _, n := range nodes {
+			if r.selects(n) {
+				selected = append(selected, n)
+			}
+			walk(n.children)
+		}
+	}
+	walk(roots)
+
+	var violations []string
+	describe := func(n *spanNode) string {
+		return fmt.Sprintf("%s [%s] +%s",
+			n.span.OperationName, n.service, formatDuration(n.span.StartTime-startTime))
+	}
+
+	if r.Required && len(selected) == 0 {
+		violations = append(violations, "no matching spans")
+	}
+	if r.MinCount != nil && len(selected) < *r.MinCount {
+		violations = append(violations, fmt.Sprintf("%d calls, want at least %d", len(selected), *r.MinCount))
+	}
+	if r.MaxCount != nil && len(selected) > *r.MaxCount {
+		violations = append(violations, fmt.Sprintf("%d calls, want at most %d", len(selected), *r.MaxCount))
+	}
+	for _, n := range selected {
+		if r.NoErrors && n.hasError() {
+			v := describe(n) + " has an error"
+			if msg := n.errorMessage(); msg != "" {
+				v += ": " + msg
+			}
+			violations = append(violations, v)
+		}
+		if d := time.Duration(n.span.Duration) * time.Microsecond; r.MaxDuration > 0 && d > r.MaxDuration {
+			violations = append(violations, fmt.Sprintf("%s took %s, want < %s",
+				describe(n), formatDuration(n.span.Duration), r.MaxDuration))
+		}
+	}
+
+	return ruleResult{Rule: r.describe(), Passed: len(violations) == 0, Violations: violations}
+}
+
+func checkTrace(rs ruleSet, t trace) checkReport {
+	roots, startTime := buildTree(t)
+	report := checkReport{TraceID: t.TraceID, Passed: true}
+	for _, r := range rs.Rules {
+		res := r.check(roots, startTime)
+		report.Passed = report.Passed && res.Passed
+		report.Results = append(report.Results, res)
+	}
+	return report
+}
+
+func printCheckReport(w io.Writer, report checkReport) {
+	for _, res := range report.Results {
+		status := "PASS"
+		if !res.Passed {
+			status = "FAIL"
+		}
+		fmt.Fprintf(w, "%s %s\n", status, res.Rule)
+		for _, v := range res.Violations {
+			fmt.Fprintf(w, "  %s\n", v)
+		}
+	}
+}
+
+func runCheck(args []string) {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	jaegerURL := fs.String("url", "http://localhost:16686", "Jaeger URL")
+	rulesFile := fs.String("rules", "", "YAML file of rules to check")
+	format := fs.String("format", "text", "report format: text or json")
+	wait := fs.Duration("wait", 0, "poll for up to this long until the trace appears and stops growing")
+	waitQuiet := fs.Duration("wait-quiet", 2*time.Second, "with -wait, how long the span count must stay unchanged")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `jtree check - assert rules against a trace
+
+Usage:
+  jtree check -rules <file> [flags] <trace-id>
+
+Exits with status 1 if any rule is violated.
+
+Example rules file:
+  rules:
+    - no_errors: true
+    - operation: checkout
+      max_duration: 500ms
+    - operation: db.query
+      max_count: 3
+    - service: inventory
+      required: true
+
+Flags:
+`)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *rulesFile == "" || fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(1)
+	}
+	if *format != "text" && *format != "json" {
+		fmt.Fprintf(os.Stderr, "invalid -format %q: must be text or json\n", *format)
+		os.Exit(1)
+	}
+
+	data, err := os.ReadFile(*rulesFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to read rules: %v\n", err)
+		os.Exit(1)
+	}
+	rs, err := parseRules(data)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	baseURL, traceID := parseInput(fs.Arg(0), *jaegerURL)
+	t, err := waitForTrace(baseURL, traceID, *wait, *waitQuiet, pollInterval)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	report := checkTrace(rs, t)
+	if *format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(report)
+	} else {
+		printCheckReport(os.Stdout, report)
+	}
+	if !report.Passed {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func checkFixture() trace {
+	spans := []span{
+		{SpanID: "root", OperationName: "checkout", StartTime: 1000, Duration: 600_000, ProcessID: "p1"},
+	}
+	for i, id := range []string{"q1", "q2", "q3", "q4"} {
+		spans = append(spans, span{
+			SpanID:        id,
+			OperationName: "db.query",
+			References:    []reference{{RefType: "CHILD_OF", SpanID: "root"}},
+			StartTime:     int64(2000 + i*1000),
+			Duration:      500,
+			ProcessID:     "p2",
+		})
+	}
+	spans[2].Tags = []tag{{Key: "error", Value: true}, {Key: "error.message", Value: "deadlock"}}
+	return trace{
+		TraceID: "trace1",
+		Spans:   spans,
+		Processes: map[string]process{
+			"p1": {ServiceName: "api"},
+			"p2": {ServiceName: "postgres"},
+		},
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    int
+		wantErr bool
+	}{
+		{
+			name: "valid rules",
+			input: `rules:
+  - no_errors: true
+  - operation: checkout
+    max_duration: 500ms
+  - operation: db.query
+    max_count: 3
+  - service: inventory
+    required: true
+`,
+			want: 4,
+		},
+		{
+			name:    "rule without assertion",
+			input:   "rules:\n  - operation: checkout\n",
+			wantErr: true,
+		},
+		{
+			name:    "no rules",
+			input:   "rules: []\n",
+			wantErr: true,
+		},
+		{
+			name:    "misspelt key",
+			input:   "rules:\n  - max_count: 3\n    max_duraton: 1ms\n",
+			wantErr: true,
+		},
+		{
+			name:    "empty file",
+			input:   "",
+			wantErr: true,
+		},
+		{
+			name:    "invalid duration",
+			input:   "rules:\n  - max_duration: soon\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rs, err := parseRules([]byte(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseRules() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && len(rs.Rules) != tt.want {
+				t.Errorf("parseRules() returned %d rules, want %d", len(rs.Rules), tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckTrace(t *testing.T) {
+	rs, err := parseRules([]byte(`rules:
+  - no_errors: true
+  - operation: checkout
+    max_duration: 500ms
+  - name: at most 3 queries
+    operation: db.query
+    max_count: 3
+  - service: postgres
+    min_count: 2
+  - service: inventory
+    required: true
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report := checkTrace(rs, checkFixture())
+	if report.Passed {
+		t.Error("expected report to fail")
+	}
+	if report.TraceID != "trace1" {
+		t.Errorf("TraceID = %s, want trace1", report.TraceID)
+	}
+
+	var buf bytes.Buffer
+	printCheckReport(&buf, report)
+
+	want := `FAIL all spans, no errors
+  db.query [postgres] +2.00ms has an error: deadlock
+FAIL operation checkout, < 500ms
+  checkout [api] +0us took 600.00ms, want < 500ms
+FAIL at most 3 queries
+  4 calls, want at most 3
+PASS service postgres, at least 2 calls
+FAIL service inventory, required
+  no matching spans
+`
+	if got := buf.String(); got != want {
+		t.Errorf("printCheckReport() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestCheckTrace_Passing(t *testing.T) {
+	rs, err := parseRules([]byte(`rules:
+  - operation: checkout
+    max_duration: 1s
+  - service: postgres
+    required: true
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report := checkTrace(rs, checkFixture())
+	if !report.Passed {
+		t.Errorf("expected report to pass, got %+v", report.Results)
+	}
+}
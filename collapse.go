@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// repeatRun returns the end of the run of kept siblings starting at i that
+// share its service and operation.
+func repeatRun(nodes []*spanNode, keep []bool, i int) int {
+	j := i + 1
+	for j < len(nodes) && keep[j] &&
+		nodes[j].service == nodes[i].service &&
+		nodes[j].span.OperationName == nodes[i].span.OperationName {
+		j++
+	}
+	return j
+}
+
+// foldable reports whether a run of siblings can be folded into one line.
+// Folding drops the children, so a run is printed in full when any of its
+// subtrees has an error, or when filters are set and a span below the run
+// matches them.
+func foldable(nodes []*spanNode, cfg *config) bool {
+	for _, n := range nodes {
+		if n.subtreeHasError() || !n.matchesSelf(cfg) {
+			return false
+		}
+		if !cfg.filtering() {
+			continue
+		}
+		for _, child := range n.children {
+			if child.matchesFilter(cfg) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// subtreeHasError reports whether the span or any of its descendants failed.
+func (n *spanNode) subtreeHasError() bool {
+	if n.hasError() {
+		return true
+	}
+	for _, child := range n.children {
+		if child.subtreeHasError() {
+			return true
+		}
+	}
+	return false
+}
+
+// printCollapsed prints a run of repeated siblings as a single line, without
+// their children.
+func printCollapsed(w io.Writer, nodes []*spanNode, prefix string, startTime int64, cfg *config) {
+	durations := make([]int64, len(nodes))
+	var total int64
+	start, end := nodes[0].span.StartTime, int64(0)
+	matched := false
+	for i, n := range nodes {
+		durations[i] = n.span.Duration
+		total += n.span.Duration
+		start = min(start, n.span.StartTime)
+		end = max(end, n.span.StartTime+n.span.Duration)
+		matched = matched || n.matchesSelf(cfg)
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+
+	indent := linePrefix(prefix, matched, cfg)
+	first := nodes[0]
+	p50 := formatDuration(percentile(durations, 50))
+	maxDuration := formatDuration(durations[len(durations)-1])
+
+	if cfg.jsonOutput {
+		out := map[string]any{
+			"service": first.service,
+			"count":   len(nodes),
+			"total":   formatDuration(total),
+			"p50":     p50,
+			"max":     maxDuration,
+		}
+		jsonBytes, _ := json.Marshal(out)
+		fmt.Fprintf(w, "%s%s %s\n", indent, first.span.OperationName, string(jsonBytes))
+		return
+	}
+
+	_, light := cfg.barFill()
+	fmt.Fprintf(w, "%s%s%s [%s] ×%d total %s, p50 %s, max %s\n",
+		cfg.bar(start, end-start, startTime, light, serviceColor(first.service)),
+		indent,
+		first.span.OperationName,
+		paint(first.service, serviceColor(first.service), cfg),
+		len(nodes),
+		formatDuration(total),
+		p50,
+		maxDuration,
+	)
+}
+
+// percentile returns the nearest-rank pth percentile of sorted values.
+func percentile(sorted []int64, p float64) int64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	rank = min(max(rank, 1), len(sorted))
+	return sorted[rank-1]
+}
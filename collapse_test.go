@@ -0,0 +1,270 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestPercentile(t *testing.T) {
+	values := []int64{10, 20, 30, 40, 50, 60, 70, 80, 90, 100}
+
+	tests := []struct {
+		p        float64
+		expected int64
+	}{
+		{p: 0, expected: 10},
+		{p: 50, expected: 50},
+		{p: 90, expected: 90},
+		{p: 99, expected: 100},
+		{p: 100, expected: 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("p%v", tt.p), func(t *testing.T) {
+			if got := percentile(values, tt.p); got != tt.expected {
+				t.Errorf("percentile(%v) = %d, want %d", tt.p, got, tt.expected)
+			}
+		})
+	}
+
+	if got := percentile(nil, 50); got != 0 {
+		t.Errorf("percentile(nil) = %d, want 0", got)
+	}
+}
+
+func TestPrintRoots_Collapse(t *testing.T) {
+	spans := []span{
+		{SpanID: "root", OperationName: "GET /orders", StartTime: 1000, Duration: 100_000, ProcessID: "p1"},
+		{
+			SpanID:        "auth",
+			OperationName: "auth",
+			References:    []reference{{RefType: "CHILD_OF", SpanID: "root"}},
+			StartTime:     1100,
+			Duration:      500,
+			ProcessID:     "p1",
+		},
+	}
+	for i := range 6 {
+		spans = append(spans, span{
+			SpanID:        fmt.Sprintf("q%d", i),
+			OperationName: "db.query",
+			References:    []reference{{RefType: "CHILD_OF", SpanID: "root"}},
+			StartTime:     int64(2000 + i*10_000),
+			Duration:      int64(1000 * (i + 1)),
+			ProcessID:     "p2",
+		})
+	}
+	tr := trace{
+		TraceID: "trace1",
+		Spans:   spans,
+		Processes: map[string]process{
+			"p1": {ServiceName: "api"},
+			"p2": {ServiceName: "postgres"},
+		},
+	}
+
+	tests := []struct {
+		name string
+		cfg  *config
+		want string
+	}{
+		{
+			name: "run at threshold is folded",
+			cfg:  &config{relativeTime: true, collapse: 6},
+			want: `GET /orders [api] +0us 100.00ms (self 78.50ms)
+  auth [api] +100us 500us
+  db.query [postgres] ×6 total 21.00ms, p50 3.00ms, max 6.00ms
+`,
+		},
+		{
+			name: "run below threshold is printed",
+			cfg:  &config{relativeTime: true, collapse: 7},
+			want: `GET /orders [api] +0us 100.00ms (self 78.50ms)
+  auth [api] +100us 500us
+  db.query [postgres] +1.00ms 1.00ms
+  db.query [postgres] +11.00ms 2.00ms
+  db.query [postgres] +21.00ms 3.00ms
+  db.query [postgres] +31.00ms 4.00ms
+  db.query [postgres] +41.00ms 5.00ms
+  db.query [postgres] +51.00ms 6.00ms
+`,
+		},
+		{
+			name: "filters apply before folding",
+			cfg:  &config{relativeTime: true, collapse: 3, minDuration: 3_000_000},
+			want: `GET /orders [api] +0us 100.00ms (self 78.50ms)
+  db.query [postgres] ×4 total 18.00ms, p50 4.00ms, max 6.00ms
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			roots, startTime := buildTree(tr)
+			computeSelfTimes(roots)
+			var buf bytes.Buffer
+			printRoots(&buf, roots, startTime, tt.cfg)
+			if got := buf.String(); got != tt.want {
+				t.Errorf("printRoots() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrintRoots_CollapseKeepsErrors(t *testing.T) {
+	spans := []span{
+		{SpanID: "root", OperationName: "GET /orders", StartTime: 1000, Duration: 100_000, ProcessID: "p1"},
+	}
+	for i := range 5 {
+		spans = append(spans, span{
+			SpanID:        fmt.Sprintf("h%d", i),
+			OperationName: "handle",
+			References:    []reference{{RefType: "CHILD_OF", SpanID: "root"}},
+			StartTime:     int64(2000 + i*10_000),
+			Duration:      5000,
+			ProcessID:     "p1",
+		})
+	}
+	spans = append(spans, span{
+		SpanID:        "db",
+		OperationName: "db.query",
+		References:    []reference{{RefType: "CHILD_OF", SpanID: "h2"}},
+		StartTime:     22_500,
+		Duration:      1000,
+		ProcessID:     "p2",
+		Tags:          []tag{{Key: "error", Value: true}},
+	})
+	tr := trace{
+		TraceID: "trace1",
+		Spans:   spans,
+		Processes: map[string]process{
+			"p1": {ServiceName: "api"},
+			"p2": {ServiceName: "postgres"},
+		},
+	}
+
+	tests := []struct {
+		name string
+		cfg  *config
+		want string
+	}{
+		{
+			name: "run with an error below is not folded",
+			cfg:  &config{relativeTime: true, collapse: 5},
+			want: `GET /orders [api] +0us 100.00ms (self 75.00ms)
+  handle [api] +1.00ms 5.00ms
+  handle [api] +11.00ms 5.00ms
+  handle [api] +21.00ms 5.00ms (self 4.00ms)
+    db.query [postgres] +21.50ms 1.00ms
+  handle [api] +31.00ms 5.00ms
+  handle [api] +41.00ms 5.00ms
+`,
+		},
+		{
+			name: "ancestors of matches are not folded",
+			cfg:  &config{relativeTime: true, collapse: 1, service: "postgres"},
+			want: `GET /orders [api] +0us 100.00ms (self 75.00ms)
+  handle [api] +21.00ms 5.00ms (self 4.00ms)
+    db.query [postgres] +21.50ms 1.00ms
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			roots, startTime := buildTree(tr)
+			computeSelfTimes(roots)
+			var buf bytes.Buffer
+			printRoots(&buf, roots, startTime, tt.cfg)
+			if got := buf.String(); got != tt.want {
+				t.Errorf("printRoots() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrintRoots_CollapseKeepsDescendantMatches(t *testing.T) {
+	spans := []span{
+		{SpanID: "root", OperationName: "GET /orders", StartTime: 1000, Duration: 100_000, ProcessID: "p1"},
+	}
+	for i := range 5 {
+		spans = append(spans,
+			span{
+				SpanID:        fmt.Sprintf("h%d", i),
+				OperationName: "handle",
+				References:    []reference{{RefType: "CHILD_OF", SpanID: "root"}},
+				StartTime:     int64(2000 + i*5000),
+				Duration:      5000,
+				ProcessID:     "p1",
+			},
+			span{
+				SpanID:        fmt.Sprintf("q%d", i),
+				OperationName: "db.query",
+				References:    []reference{{RefType: "CHILD_OF", SpanID: fmt.Sprintf("h%d", i)}},
+				StartTime:     int64(3000 + i*5000),
+				Duration:      2000,
+				ProcessID:     "p2",
+			},
+		)
+	}
+	tr := trace{
+		TraceID: "trace1",
+		Spans:   spans,
+		Processes: map[string]process{
+			"p1": {ServiceName: "api"},
+			"p2": {ServiceName: "postgres"},
+		},
+	}
+
+	unfolded := `GET /orders [api] +0us 100.00ms (self 75.00ms)
+  handle [api] +1.00ms 5.00ms (self 3.00ms)
+    db.query [postgres] +2.00ms 2.00ms
+  handle [api] +6.00ms 5.00ms (self 3.00ms)
+    db.query [postgres] +7.00ms 2.00ms
+  handle [api] +11.00ms 5.00ms (self 3.00ms)
+    db.query [postgres] +12.00ms 2.00ms
+  handle [api] +16.00ms 5.00ms (self 3.00ms)
+    db.query [postgres] +17.00ms 2.00ms
+  handle [api] +21.00ms 5.00ms (self 3.00ms)
+    db.query [postgres] +22.00ms 2.00ms
+`
+
+	tests := []struct {
+		name string
+		cfg  *config
+		want string
+	}{
+		{
+			name: "without filters the run folds",
+			cfg:  &config{relativeTime: true, collapse: 5},
+			want: `GET /orders [api] +0us 100.00ms (self 75.00ms)
+  handle [api] ×5 total 25.00ms, p50 5.00ms, max 5.00ms
+`,
+		},
+		{
+			name: "matching children are not folded away",
+			cfg:  &config{relativeTime: true, collapse: 5, minDuration: time.Millisecond},
+			want: unfolded,
+		},
+		{
+			name: "critical path through a sequential loop",
+			cfg:  &config{relativeTime: true, collapse: 5, criticalPath: true},
+			want: unfolded,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			roots, startTime := buildTree(tr)
+			computeSelfTimes(roots)
+			markCriticalPath(roots)
+			var buf bytes.Buffer
+			printRoots(&buf, roots, startTime, tt.cfg)
+			if got := buf.String(); got != tt.want {
+				t.Errorf("printRoots() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
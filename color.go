@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+)
+
+const (
+	colorReset = "\033[0m"
+	colorDim   = "\033[2m"
+	colorBold  = "\033[1m"
+	colorRed   = "\033[31m"
+)
+
+// servicePalette excludes red, which is reserved for errors.
+var servicePalette = []string{
+	"\033[32m", "\033[33m", "\033[34m", "\033[35m", "\033[36m",
+	"\033[92m", "\033[93m", "\033[94m", "\033[95m", "\033[96m",
+}
+
+// resolveColor decides whether to colour output for the -color mode. In
+// auto mode colour is used when writing to a terminal and NO_COLOR is unset.
+func resolveColor(mode string, tty bool, noColor bool) (bool, error) {
+	switch mode {
+	case "always":
+		return true, nil
+	case "never":
+		return false, nil
+	case "auto":
+		return tty && !noColor, nil
+	default:
+		return false, fmt.Errorf("invalid -color %q: must be always, never or auto", mode)
+	}
+}
+
+// paint wraps s in the escape code when colour is enabled.
+func paint(s, code string, cfg *config) string {
+	if !cfg.useColor || code == "" {
+		return s
+	}
+	return code + s + colorReset
+}
+
+// serviceColor returns a colour for the service that is the same on every
+// run, so a service can be recognised across traces.
+func serviceColor(service string) string {
+	h := fnv.New32a()
+	h.Write([]byte(service))
+	return servicePalette[h.Sum32()%uint32(len(servicePalette))]
+}
+
+// durationColor shades a duration by the share of the trace it took: dim for
+// under 1%, plain up to 10%, then increasingly hot.
+func durationColor(duration, total int64) string {
+	if total <= 0 {
+		return ""
+	}
+	switch share := float64(duration) / float64(total); {
+	case share >= 0.5:
+		return colorBold + "\033[38;5;196m"
+	case share >= 0.25:
+		return "\033[38;5;208m"
+	case share >= 0.1:
+		return "\033[38;5;220m"
+	case share < 0.01:
+		return colorDim
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestResolveColor(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    string
+		tty     bool
+		noColor bool
+		want    bool
+		wantErr bool
+	}{
+		{name: "auto on terminal", mode: "auto", tty: true, want: true},
+		{name: "auto piped", mode: "auto", tty: false, want: false},
+		{name: "auto with NO_COLOR", mode: "auto", tty: true, noColor: true, want: false},
+		{name: "always overrides NO_COLOR", mode: "always", noColor: true, want: true},
+		{name: "never on terminal", mode: "never", tty: true, want: false},
+		{name: "invalid", mode: "sometimes", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveColor(tt.mode, tt.tty, tt.noColor)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveColor() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("resolveColor() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServiceColor_Stable(t *testing.T) {
+	for _, svc := range []string{"frontend", "backend", "db", ""} {
+		c := serviceColor(svc)
+		if c != serviceColor(svc) {
+			t.Errorf("serviceColor(%q) not stable", svc)
+		}
+		if c == colorRed {
+			t.Errorf("serviceColor(%q) = red, reserved for errors", svc)
+		}
+	}
+}
+
+func TestDurationColor(t *testing.T) {
+	tests := []struct {
+		duration, total int64
+		want            string
+	}{
+		{duration: 600, total: 1000, want: colorBold + "\033[38;5;196m"},
+		{duration: 300, total: 1000, want: "\033[38;5;208m"},
+		{duration: 150, total: 1000, want: "\033[38;5;220m"},
+		{duration: 50, total: 1000, want: ""},
+		{duration: 5, total: 1000, want: colorDim},
+		{duration: 5, total: 0, want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := durationColor(tt.duration, tt.total); got != tt.want {
+			t.Errorf("durationColor(%d, %d) = %q, want %q", tt.duration, tt.total, got, tt.want)
+		}
+	}
+}
+
+func TestPrintSpan_Color(t *testing.T) {
+	node := &spanNode{
+		span: span{
+			OperationName: "GET /users",
+			StartTime:     1000,
+			Duration:      1000,
+			Tags:          []tag{{Key: "error", Value: true}},
+		},
+		service: "frontend",
+	}
+
+	var plain bytes.Buffer
+	printSpan(&plain, node, "", 1000, &config{relativeTime: true, traceLength: 1000})
+	if strings.Contains(plain.String(), "\033[") {
+		t.Errorf("uncoloured output contains escape codes: %q", plain.String())
+	}
+
+	var colored bytes.Buffer
+	printSpan(&colored, node, "", 1000, &config{relativeTime: true, traceLength: 1000, useColor: true})
+	out := colored.String()
+	for _, want := range []string{
+		colorRed + "GET /users" + colorReset,
+		serviceColor("frontend") + "frontend" + colorReset,
+		colorDim + "+0us" + colorReset,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("coloured output %q missing %q", out, want)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+type concurrencyStats struct {
+	maxConcurrent int
+	avgConcurrent float64
+	// sequential holds the runs of at least two children that ran one after
+	// another, each starting after the previous finished.
+	sequential [][]*spanNode
+}
+
+// concurrency returns how concurrently the span's children ran. The average
+// is taken over the time at least one child was running.
+func (n *spanNode) concurrency() concurrencyStats {
+	stats := concurrencyStats{maxConcurrent: maxConcurrency(n.children)}
+
+	start := n.span.StartTime
+	end := start + n.span.Duration
+	var busy, covered int64
+	for _, child := range n.children {
+		s := max(child.span.StartTime, start)
+		e := min(child.span.StartTime+child.span.Duration, end)
+		busy += max(e-s, 0)
+	}
+	for _, iv := range n.childIntervals() {
+		covered += iv.end - iv.start
+	}
+	if covered > 0 {
+		stats.avgConcurrent = float64(busy) / float64(covered)
+	}
+
+	// In start order, a run continues while each child starts after every
+	// earlier child in the run has finished. -sort may have reordered the
+	// children, so sort a copy.
+	children := slices.Clone(n.children)
+	slices.SortStableFunc(children, func(a, b *spanNode) int {
+		return cmp.Compare(a.span.StartTime, b.span.StartTime)
+	})
+	var run []*spanNode
+	var runEnd int64
+	flush := func() {
+		if len(run) >= 2 {
+			stats.sequential = append(stats.sequential, run)
+		}
+		run, runEnd = nil, 0
+	}
+	for _, child := range children {
+		if len(run) > 0 && child.span.StartTime < runEnd {
+			flush()
+		}
+		run = append(run, child)
+		runEnd = max(runEnd, child.span.StartTime+child.span.Duration)
+	}
+	flush()
+
+	return stats
+}
+
+// parallelSaving returns how much shorter a sequential run would have been if
+// its calls had run in parallel.
+func parallelSaving(run []*spanNode) int64 {
+	var total, longest int64
+	for _, n := range run {
+		total += n.span.Duration
+		longest = max(longest, n.span.Duration)
+	}
+	return total - longest
+}
+
+func printConcurrency(w io.Writer, roots []*spanNode, cfg *config) {
+	var walk func(nodes []*spanNode)
+	walk = func(nodes []*spanNode) {
+		for _, n := range nodes {
+			if len(n.children) >= 2 && n.matchesSelf(cfg) {
+				stats := n.concurrency()
+				fmt.Fprintf(w, "%s [%s] %d children\n", n.span.OperationName, n.service, len(n.children))
+				fmt.Fprintf(w, "  max concurrency %d, avg %.2f\n", stats.maxConcurrent, stats.avgConcurrent)
+				for _, run := range stats.sequential {
+					names := make([]string, len(run))
+					var total int64
+					for i, r := range run {
+						names[i] = fmt.Sprintf("%s [%s]", r.span.OperationName, r.service)
+						total += r.span.Duration
+					}
+					fmt.Fprintf(w, "  sequential %s: %s, could save %s if parallel\n",
+						formatDuration(total),
+						strings.Join(names, " → "),
+						formatDuration(parallelSaving(run)),
+					)
+				}
+			}
+			walk(n.children)
+		}
+	}
+	walk(roots)
+}
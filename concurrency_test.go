@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func concurrencyNode() *spanNode {
+	child := func(op string, start, duration int64) *spanNode {
+		return &spanNode{span: span{OperationName: op, StartTime: start, Duration: duration}, service: "svc"}
+	}
+	return &spanNode{
+		span:    span{OperationName: "parent", StartTime: 1000, Duration: 2000},
+		service: "svc",
+		children: []*spanNode{
+			// sequential run
+			child("a", 1000, 200),
+			child("b", 1200, 300),
+			child("c", 1500, 100),
+			// three in parallel
+			child("d", 2000, 400),
+			child("e", 2000, 400),
+			child("f", 2100, 200),
+		},
+	}
+}
+
+func TestSpanNode_concurrency(t *testing.T) {
+	stats := concurrencyNode().concurrency()
+
+	if stats.maxConcurrent != 3 {
+		t.Errorf("maxConcurrent = %d, want 3", stats.maxConcurrent)
+	}
+	// 1600us of child time over 1000us where a child was running
+	if stats.avgConcurrent != 1.6 {
+		t.Errorf("avgConcurrent = %v, want 1.6", stats.avgConcurrent)
+	}
+	if len(stats.sequential) != 1 {
+		t.Fatalf("expected 1 sequential run, got %d", len(stats.sequential))
+	}
+	run := stats.sequential[0]
+	if len(run) != 4 || run[0].span.OperationName != "a" || run[3].span.OperationName != "d" {
+		t.Errorf("unexpected sequential run of %d starting %s", len(run), run[0].span.OperationName)
+	}
+	if got := parallelSaving(run); got != 600 {
+		t.Errorf("parallelSaving() = %d, want 600", got)
+	}
+}
+
+func TestSpanNode_concurrency_NoChildren(t *testing.T) {
+	stats := (&spanNode{span: span{StartTime: 1000, Duration: 100}}).concurrency()
+
+	if stats.maxConcurrent != 0 || stats.avgConcurrent != 0 || len(stats.sequential) != 0 {
+		t.Errorf("unexpected stats for leaf span: %+v", stats)
+	}
+}
+
+func TestPrintConcurrency(t *testing.T) {
+	var buf bytes.Buffer
+	printConcurrency(&buf, []*spanNode{concurrencyNode()}, &config{})
+
+	want := `parent [svc] 6 children
+  max concurrency 3, avg 1.60
+  sequential 1.00ms: a [svc] → b [svc] → c [svc] → d [svc], could save 600us if parallel
+`
+	if got := buf.String(); got != want {
+		t.Errorf("printConcurrency() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestSpanNode_concurrency_Sorted(t *testing.T) {
+	n := concurrencyNode()
+	sortTree([]*spanNode{n}, "duration")
+	stats := n.concurrency()
+
+	if len(stats.sequential) != 1 {
+		t.Fatalf("expected 1 sequential run, got %d", len(stats.sequential))
+	}
+	var ops []string
+	for _, s := range stats.sequential[0] {
+		ops = append(ops, s.span.OperationName)
+	}
+	if got := strings.Join(ops, " → "); got != "a → b → c → d" {
+		t.Errorf("sequential run = %s, want a → b → c → d", got)
+	}
+}
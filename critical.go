@@ -0,0 +1,37 @@
+package main
+
+// markCriticalPath flags the spans that determined the end-to-end latency of
+// the trace. Starting from the end of the trace, the span that finished last
+// is critical; the walk then continues from that span's start to find the
+// next one. The same walk is repeated inside each critical span over its
+// children, so time hidden behind a longer parallel sibling is not critical.
+func markCriticalPath(roots []*spanNode) {
+	var end int64
+	for _, root := range roots {
+		end = max(end, root.span.StartTime+root.span.Duration)
+	}
+	markCritical(roots, end)
+}
+
+func markCritical(nodes []*spanNode, cursor int64) {
+	for {
+		var best *spanNode
+		var bestEnd int64
+		for _, node := range nodes {
+			if node.span.StartTime >= cursor {
+				continue
+			}
+			nodeEnd := min(node.span.StartTime+node.span.Duration, cursor)
+			if best == nil || nodeEnd > bestEnd {
+				best, bestEnd = node, nodeEnd
+			}
+		}
+		if best == nil {
+			return
+		}
+
+		best.critical = true
+		markCritical(best.children, bestEnd)
+		cursor = best.span.StartTime
+	}
+}
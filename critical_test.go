@@ -0,0 +1,65 @@
+package main
+
+import "testing"
+
+func TestMarkCriticalPath(t *testing.T) {
+	tr := trace{
+		TraceID: "trace1",
+		Spans: []span{
+			// root: 1000-2000
+			{SpanID: "root", StartTime: 1000, Duration: 1000},
+			// parallel fan-out; slow ends last and hides fast
+			{SpanID: "fast", References: []reference{{RefType: "CHILD_OF", SpanID: "root"}}, StartTime: 1100, Duration: 200},
+			{SpanID: "slow", References: []reference{{RefType: "CHILD_OF", SpanID: "root"}}, StartTime: 1100, Duration: 500},
+			// slow's children run sequentially, both critical
+			{SpanID: "slow-a", References: []reference{{RefType: "CHILD_OF", SpanID: "slow"}}, StartTime: 1150, Duration: 100},
+			{SpanID: "slow-b", References: []reference{{RefType: "CHILD_OF", SpanID: "slow"}}, StartTime: 1300, Duration: 200},
+			// runs after slow, critical
+			{SpanID: "tail", References: []reference{{RefType: "CHILD_OF", SpanID: "root"}}, StartTime: 1700, Duration: 200},
+			// fire-and-forget before tail ends but overlapped by tail
+			{SpanID: "overlapped", References: []reference{{RefType: "CHILD_OF", SpanID: "root"}}, StartTime: 1750, Duration: 50},
+			// outlives its parent; clipped to parent end and still critical
+			{SpanID: "late", References: []reference{{RefType: "CHILD_OF", SpanID: "tail"}}, StartTime: 1850, Duration: 500},
+		},
+	}
+
+	roots, _ := buildTree(tr)
+	markCriticalPath(roots)
+
+	want := map[string]bool{
+		"root":       true,
+		"fast":       false,
+		"slow":       true,
+		"slow-a":     true,
+		"slow-b":     true,
+		"tail":       true,
+		"overlapped": false,
+		"late":       true,
+	}
+
+	var walk func(nodes []*spanNode)
+	walk = func(nodes []*spanNode) {
+		for _, n := range nodes {
+			if n.critical != want[n.span.SpanID] {
+				t.Errorf("%s.critical = %v, want %v", n.span.SpanID, n.critical, want[n.span.SpanID])
+			}
+			walk(n.children)
+		}
+	}
+	walk(roots)
+}
+
+func TestMarkCriticalPath_MultipleRoots(t *testing.T) {
+	early := &spanNode{span: span{SpanID: "early", StartTime: 1000, Duration: 100}}
+	covered := &spanNode{span: span{SpanID: "covered", StartTime: 1050, Duration: 100}}
+	late := &spanNode{span: span{SpanID: "late", StartTime: 1000, Duration: 500}}
+
+	markCriticalPath([]*spanNode{early, covered, late})
+
+	if !late.critical {
+		t.Error("expected latest-ending root to be critical")
+	}
+	if early.critical || covered.critical {
+		t.Error("expected roots hidden by the latest-ending root not to be critical")
+	}
+}
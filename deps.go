@@ -0,0 +1,131 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+type depEdge struct {
+	from, to string
+	calls    int
+	total    int64
+	errors   int
+}
+
+// buildDeps derives service calls from the trees: an edge from A to B for
+// every span of service B whose parent belongs to service A. Calls within a
+// service are ignored. Edges are sorted by caller and callee.
+func buildDeps(trees [][]*spanNode) []*depEdge {
+	edges := make(map[[2]string]*depEdge)
+	var walk func(parent *spanNode, nodes []*spanNode)
+	walk = func(parent *spanNode, nodes []*spanNode) {
+		for _, n := range nodes {
+			if parent != nil && parent.service != n.service {
+				key := [2]string{parent.service, n.service}
+				e, ok := edges[key]
+				if !ok {
+					e = &depEdge{from: parent.service, to: n.service}
+					edges[key] = e
+				}
+				e.calls++
+				e.total += n.span.Duration
+				if n.hasError() {
+					e.errors++
+				}
+			}
+			walk(n, n.children)
+		}
+	}
+	for _, roots := range trees {
+		walk(nil, roots)
+	}
+
+	out := make([]*depEdge, 0, len(edges))
+	for _, e := range edges {
+		out = append(out, e)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].from != out[j].from {
+			return out[i].from < out[j].from
+		}
+		return out[i].to < out[j].to
+	})
+	return out
+}
+
+func (e *depEdge) label(sep string) string {
+	parts := []string{fmt.Sprintf("%d calls", e.calls), formatDuration(e.total)}
+	if e.errors > 0 {
+		parts = append(parts, fmt.Sprintf("%d errors", e.errors))
+	}
+	return strings.Join(parts, sep)
+}
+
+func printDeps(w io.Writer, edges []*depEdge, format string) {
+	switch format {
+	case "dot":
+		fmt.Fprintln(w, "digraph services {")
+		for _, e := range edges {
+			fmt.Fprintf(w, "  %q -> %q [label=%q];\n", e.from, e.to, e.label("\n"))
+		}
+		fmt.Fprintln(w, "}")
+
+	case "mermaid":
+		// Service names may contain characters mermaid does not allow in
+		// node IDs, so nodes get generated IDs with the name as label.
+		ids := make(map[string]string)
+		id := func(service string) string {
+			if v, ok := ids[service]; ok {
+				return v
+			}
+			v := fmt.Sprintf("s%d", len(ids))
+			ids[service] = v
+			return fmt.Sprintf("%s[%q]", v, service)
+		}
+		fmt.Fprintln(w, "graph LR")
+		for _, e := range edges {
+			fmt.Fprintf(w, "  %s -->|%s| %s\n", id(e.from), e.label(", "), id(e.to))
+		}
+
+	default:
+		for _, e := range edges {
+			fmt.Fprintf(w, "%s -> %s %s\n", e.from, e.to, e.label(", "))
+		}
+	}
+}
+
+func runDeps(args []string) {
+	var src traceSource
+	fs := flag.NewFlagSet("deps", flag.ExitOnError)
+	src.register(fs)
+	format := fs.String("format", "text", "output format: text, dot or mermaid")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `jtree deps - extract the service dependency graph from traces
+
+Usage:
+  jtree deps [flags] <trace-id>...
+  jtree deps [flags] -          (read trace IDs from stdin)
+  jtree deps -service <service> [-operation <op>] [flags]
+
+An edge A -> B is counted for every span of service B whose parent span
+belongs to service A.
+
+Flags:
+`)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	switch *format {
+	case "text", "dot", "mermaid":
+	default:
+		fmt.Fprintf(os.Stderr, "invalid -format %q: must be text, dot or mermaid\n", *format)
+		os.Exit(1)
+	}
+
+	printDeps(os.Stdout, buildDeps(loadTrees(&src, fs)), *format)
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func depsFixture() [][]*spanNode {
+	errTag := tag{Key: "error", Value: true}
+	tr := trace{
+		Spans: []span{
+			{SpanID: "root", OperationName: "GET /checkout", StartTime: 1000, Duration: 10_000, ProcessID: "p1"},
+			{
+				SpanID:        "local",
+				OperationName: "validate",
+				References:    []reference{{RefType: "CHILD_OF", SpanID: "root"}},
+				StartTime:     1100,
+				Duration:      100,
+				ProcessID:     "p1",
+			},
+			{
+				SpanID:        "q1",
+				OperationName: "db.query",
+				References:    []reference{{RefType: "CHILD_OF", SpanID: "local"}},
+				StartTime:     1150,
+				Duration:      1_000,
+				ProcessID:     "p2",
+			},
+			{
+				SpanID:        "q2",
+				OperationName: "db.query",
+				References:    []reference{{RefType: "CHILD_OF", SpanID: "root"}},
+				StartTime:     3000,
+				Duration:      2_000,
+				ProcessID:     "p2",
+				Tags:          []tag{errTag},
+			},
+			{
+				SpanID:        "pay",
+				OperationName: "charge",
+				References:    []reference{{RefType: "CHILD_OF", SpanID: "root"}},
+				StartTime:     6000,
+				Duration:      3_000,
+				ProcessID:     "p3",
+			},
+		},
+		Processes: map[string]process{
+			"p1": {ServiceName: "api"},
+			"p2": {ServiceName: "postgres"},
+			"p3": {ServiceName: "payments"},
+		},
+	}
+	roots, _ := buildTree(tr)
+	return [][]*spanNode{roots, roots}
+}
+
+func TestBuildDeps(t *testing.T) {
+	edges := buildDeps(depsFixture())
+
+	if len(edges) != 2 {
+		t.Fatalf("expected 2 edges, got %d", len(edges))
+	}
+	pay := edges[0]
+	if pay.from != "api" || pay.to != "payments" || pay.calls != 2 || pay.total != 6_000 || pay.errors != 0 {
+		t.Errorf("unexpected payments edge %+v", *pay)
+	}
+	pg := edges[1]
+	if pg.from != "api" || pg.to != "postgres" || pg.calls != 4 || pg.total != 6_000 || pg.errors != 2 {
+		t.Errorf("unexpected postgres edge %+v", *pg)
+	}
+}
+
+func TestPrintDeps(t *testing.T) {
+	edges := buildDeps(depsFixture())
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{
+			format: "text",
+			want: `api -> payments 2 calls, 6.00ms
+api -> postgres 4 calls, 6.00ms, 2 errors
+`,
+		},
+		{
+			format: "dot",
+			want: `digraph services {
+  "api" -> "payments" [label="2 calls\n6.00ms"];
+  "api" -> "postgres" [label="4 calls\n6.00ms\n2 errors"];
+}
+`,
+		},
+		{
+			format: "mermaid",
+			want: `graph LR
+  s0["api"] -->|2 calls, 6.00ms| s1["payments"]
+  s0 -->|4 calls, 6.00ms, 2 errors| s2["postgres"]
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			printDeps(&buf, edges, tt.format)
+			if got := buf.String(); got != tt.want {
+				t.Errorf("printDeps() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// diffNode pairs a span from trace A with the span at the same position in
+// trace B. Either side is nil when the span only exists in one trace.
+type diffNode struct {
+	a, b     *spanNode
+	children []*diffNode
+}
+
+func (d *diffNode) node() *spanNode {
+	if d.a != nil {
+		return d.a
+	}
+	return d.b
+}
+
+// delta returns the change in duration from A to B. Spans missing on one side
+// count as zero duration there.
+func (d *diffNode) delta() int64 {
+	var a, b int64
+	if d.a != nil {
+		a = d.a.span.Duration
+	}
+	if d.b != nil {
+		b = d.b.span.Duration
+	}
+	return b - a
+}
+
+// changed reports whether the span or anything beneath it differs by at
+// least minDelta or has been added, removed or changed error status.
+func (d *diffNode) changed(minDelta int64) bool {
+	if d.a == nil || d.b == nil || d.a.hasError() != d.b.hasError() {
+		return true
+	}
+	if delta := d.delta(); delta >= minDelta || -delta >= minDelta {
+		return true
+	}
+	for _, child := range d.children {
+		if child.changed(minDelta) {
+			return true
+		}
+	}
+	return false
+}
+
+// diffTrees aligns two trees by the path of service and operation names.
+// Siblings with the same service and operation are paired in start order.
+func diffTrees(a, b []*spanNode) []*diffNode {
+	type key struct{ service, operation string }
+	keyOf := func(n *spanNode) key { return key{n.service, n.span.OperationName} }
+
+	unmatched := make(map[key][]*spanNode)
+	for _, n := range b {
+		k := keyOf(n)
+		unmatched[k] = append(unmatched[k], n)
+	}
+
+	var out []*diffNode
+	paired := make(map[*spanNode]bool)
+	for _, n := range a {
+		d := &diffNode{a: n}
+		if candidates := unmatched[keyOf(n)]; len(candidates) > 0 {
+			d.b = candidates[0]
+			unmatched[keyOf(n)] = candidates[1:]
+			paired[d.b] = true
+		}
+		out = append(out, d)
+	}
+	for _, n := range b {
+		if !paired[n] {
+			out = append(out, &diffNode{b: n})
+		}
+	}
+
+	for _, d := range out {
+		var ac, bc []*spanNode
+		if d.a != nil {
+			ac = d.a.children
+		}
+		if d.b != nil {
+			bc = d.b.children
+		}
+		d.children = diffTrees(ac, bc)
+	}
+	return out
+}
+
+func printDiff(w io.Writer, nodes []*diffNode, depth int, minDelta int64) {
+	for _, d := range nodes {
+		if !d.changed(minDelta) {
+			continue
+		}
+
+		n := d.node()
+		indent := strings.Repeat("  ", depth)
+		switch {
+		case d.b == nil:
+			fmt.Fprintf(w, "- %s%s [%s] %s\n", indent, n.span.OperationName, n.service, formatDuration(d.a.span.Duration))
+		case d.a == nil:
+			fmt.Fprintf(w, "+ %s%s [%s] %s\n", indent, n.span.OperationName, n.service, formatDuration(d.b.span.Duration))
+		default:
+			fmt.Fprintf(w, "  %s%s [%s] %s → %s %s",
+				indent,
+				n.span.OperationName,
+				n.service,
+				formatDuration(d.a.span.Duration),
+				formatDuration(d.b.span.Duration),
+				formatDelta(d.delta()),
+			)
+			if d.a.span.Duration > 0 {
+				fmt.Fprintf(w, " (%+.1f%%)", float64(d.delta())/float64(d.a.span.Duration)*100)
+			}
+			if ea, eb := d.a.hasError(), d.b.hasError(); ea != eb {
+				fmt.Fprintf(w, " error: %s → %s", errorStatus(ea), errorStatus(eb))
+			}
+			fmt.Fprintln(w)
+		}
+
+		printDiff(w, d.children, depth+1, minDelta)
+	}
+}
+
+func formatDelta(us int64) string {
+	if us < 0 {
+		return "-" + formatDuration(-us)
+	}
+	return "+" + formatDuration(us)
+}
+
+func errorStatus(hasError bool) string {
+	if hasError {
+		return "error"
+	}
+	return "ok"
+}
+
+func runDiff(args []string) {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	jaegerURL := fs.String("url", "http://localhost:16686", "Jaeger URL")
+	minDelta := fs.Duration(
+		"min-delta",
+		0,
+		"only show spans whose duration changed by at least this value, plus added, removed and error changes",
+	)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `jtree diff - compare two traces structurally
+
+Usage:
+  jtree diff [flags] <trace-a> <trace-b>
+
+Spans are aligned by their path of service and operation names. Lines start
+with - for spans only in A, + for spans only in B.
+
+Flags:
+`)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	var trees [2][]*spanNode
+	for i, input := range fs.Args() {
+		baseURL, traceID := parseInput(input, *jaegerURL)
+		t, err := fetchTrace(baseURL, traceID)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		trees[i], _ = buildTree(t)
+	}
+
+	printDiff(os.Stdout, diffTrees(trees[0], trees[1]), 0, minDelta.Microseconds())
+}
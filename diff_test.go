@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func diffTraces() (a, b trace) {
+	processes := map[string]process{
+		"p1": {ServiceName: "api"},
+		"p2": {ServiceName: "postgres"},
+	}
+	child := func(id, op string, start, duration int64, tags ...tag) span {
+		return span{
+			SpanID:        id,
+			OperationName: op,
+			References:    []reference{{RefType: "CHILD_OF", SpanID: "root"}},
+			StartTime:     start,
+			Duration:      duration,
+			ProcessID:     "p2",
+			Tags:          tags,
+		}
+	}
+
+	a = trace{
+		TraceID: "a",
+		Spans: []span{
+			{SpanID: "root", OperationName: "GET /checkout", StartTime: 1000, Duration: 10_000, ProcessID: "p1"},
+			child("q1", "db.query", 1100, 1_000),
+			child("q2", "db.query", 2100, 1_000),
+			child("c", "db.commit", 3100, 500),
+		},
+		Processes: processes,
+	}
+	b = trace{
+		TraceID: "b",
+		Spans: []span{
+			{SpanID: "root", OperationName: "GET /checkout", StartTime: 5000, Duration: 40_000, ProcessID: "p1"},
+			child("q1", "db.query", 5100, 1_000),
+			child("q2", "db.query", 6100, 30_000, tag{Key: "error", Value: true}),
+			child("q3", "db.query", 36100, 2_000),
+		},
+		Processes: processes,
+	}
+	return a, b
+}
+
+func TestDiffTrees(t *testing.T) {
+	ta, tb := diffTraces()
+	a, _ := buildTree(ta)
+	b, _ := buildTree(tb)
+
+	diff := diffTrees(a, b)
+	if len(diff) != 1 {
+		t.Fatalf("expected 1 root, got %d", len(diff))
+	}
+	root := diff[0]
+	if root.a == nil || root.b == nil || root.delta() != 30_000 {
+		t.Errorf("expected paired root with delta 30000, got delta %d", root.delta())
+	}
+
+	children := root.children
+	if len(children) != 4 {
+		t.Fatalf("expected 4 aligned children, got %d", len(children))
+	}
+	if children[0].a.span.SpanID != "q1" || children[0].b.span.SpanID != "q1" {
+		t.Errorf("expected first db.query paired with first db.query")
+	}
+	if children[1].a.span.SpanID != "q2" || children[1].b.span.SpanID != "q2" {
+		t.Errorf("expected second db.query paired with second db.query")
+	}
+	if children[2].a.span.SpanID != "c" || children[2].b != nil {
+		t.Errorf("expected db.commit removed")
+	}
+	if children[3].a != nil || children[3].b.span.SpanID != "q3" {
+		t.Errorf("expected third db.query added")
+	}
+}
+
+func TestPrintDiff(t *testing.T) {
+	ta, tb := diffTraces()
+	a, _ := buildTree(ta)
+	b, _ := buildTree(tb)
+	diff := diffTrees(a, b)
+
+	tests := []struct {
+		name     string
+		minDelta int64
+		want     string
+	}{
+		{
+			name:     "all spans",
+			minDelta: 0,
+			want: `  GET /checkout [api] 10.00ms → 40.00ms +30.00ms (+300.0%)
+    db.query [postgres] 1.00ms → 1.00ms +0us (+0.0%)
+    db.query [postgres] 1.00ms → 30.00ms +29.00ms (+2900.0%) error: ok → error
+-   db.commit [postgres] 500us
++   db.query [postgres] 2.00ms
+`,
+		},
+		{
+			name:     "unchanged spans hidden",
+			minDelta: 1_000,
+			want: `  GET /checkout [api] 10.00ms → 40.00ms +30.00ms (+300.0%)
+    db.query [postgres] 1.00ms → 30.00ms +29.00ms (+2900.0%) error: ok → error
+-   db.commit [postgres] 500us
++   db.query [postgres] 2.00ms
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			printDiff(&buf, diff, 0, tt.minDelta)
+			if got := buf.String(); got != tt.want {
+				t.Errorf("printDiff() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatDelta(t *testing.T) {
+	tests := []struct {
+		input    int64
+		expected string
+	}{
+		{input: 0, expected: "+0us"},
+		{input: 1500, expected: "+1.50ms"},
+		{input: -2_500_000, expected: "-2.50s"},
+	}
+
+	for _, tt := range tests {
+		if got := formatDelta(tt.input); got != tt.expected {
+			t.Errorf("formatDelta(%d) = %q, want %q", tt.input, got, tt.expected)
+		}
+	}
+}
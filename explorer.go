@@ -0,0 +1,470 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+// explorer is the state of the interactive trace view: a tree of spans that
+// can be collapsed and filtered, with one span selected.
+type explorer struct {
+	roots     []*spanNode
+	startTime int64
+	parents   map[*spanNode]*spanNode
+	collapsed map[*spanNode]bool
+	filter    string
+	typing    bool
+	rows      []treeRow
+	cursor    int
+	top       int
+	quit      bool
+}
+
+type treeRow struct {
+	node  *spanNode
+	depth int
+}
+
+func newExplorer(roots []*spanNode, startTime int64) *explorer {
+	e := &explorer{
+		roots:     roots,
+		startTime: startTime,
+		parents:   make(map[*spanNode]*spanNode),
+		collapsed: make(map[*spanNode]bool),
+	}
+	var walk func(parent *spanNode, nodes []*spanNode)
+	walk = func(parent *spanNode, nodes []*spanNode) {
+		for _, n := range nodes {
+			e.parents[n] = parent
+			walk(n, n.children)
+		}
+	}
+	walk(nil, roots)
+	e.refresh()
+	return e
+}
+
+func (e *explorer) selected() *spanNode {
+	if e.cursor < len(e.rows) {
+		return e.rows[e.cursor].node
+	}
+	return nil
+}
+
+// refresh rebuilds the visible rows, keeping the selected span selected if it
+// is still visible. While filtering, only matching spans and their ancestors
+// are shown, expanded.
+func (e *explorer) refresh() {
+	selected := e.selected()
+	e.rows = e.rows[:0]
+
+	var walk func(nodes []*spanNode, depth int)
+	walk = func(nodes []*spanNode, depth int) {
+		for _, n := range nodes {
+			if e.filter != "" && !e.subtreeMatches(n) {
+				continue
+			}
+			e.rows = append(e.rows, treeRow{node: n, depth: depth})
+			if e.filter != "" || !e.collapsed[n] {
+				walk(n.children, depth+1)
+			}
+		}
+	}
+	walk(e.roots, 0)
+
+	e.cursor = min(e.cursor, max(len(e.rows)-1, 0))
+	e.selectNode(selected)
+}
+
+func (e *explorer) selectNode(n *spanNode) {
+	for i, r := range e.rows {
+		if r.node == n {
+			e.cursor = i
+			return
+		}
+	}
+}
+
+func (e *explorer) matches(n *spanNode) bool {
+	text := strings.ToLower(n.span.OperationName + " " + n.service)
+	return strings.Contains(text, strings.ToLower(e.filter))
+}
+
+func (e *explorer) subtreeMatches(n *spanNode) bool {
+	if e.matches(n) {
+		return true
+	}
+	for _, c := range n.children {
+		if e.subtreeMatches(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// nextError selects the next (or previous) error span in tree order after
+// the selected one, wrapping around and expanding its ancestors.
+func (e *explorer) nextError(forward bool) {
+	var all []*spanNode
+	var walk func(nodes []*spanNode)
+	walk = func(nodes []*spanNode) {
+		for _, n := range nodes {
+			all = append(all, n)
+			walk(n.children)
+		}
+	}
+	walk(e.roots)
+
+	current := 0
+	for i, n := range all {
+		if n == e.selected() {
+			current = i
+		}
+	}
+	step := 1
+	if !forward {
+		step = -1
+	}
+	for i := 1; i <= len(all); i++ {
+		n := all[((current+i*step)%len(all)+len(all))%len(all)]
+		if !n.hasError() || (e.filter != "" && !e.subtreeMatches(n)) {
+			continue
+		}
+		for p := e.parents[n]; p != nil; p = e.parents[p] {
+			delete(e.collapsed, p)
+		}
+		e.refresh()
+		e.selectNode(n)
+		return
+	}
+}
+
+func (e *explorer) move(delta int) {
+	e.cursor = min(max(e.cursor+delta, 0), max(len(e.rows)-1, 0))
+}
+
+func (e *explorer) handleKey(key string) {
+	if e.typing {
+		switch key {
+		case "enter":
+			e.typing = false
+		case "esc":
+			e.typing = false
+			e.filter = ""
+			e.refresh()
+		case "backspace":
+			if r := []rune(e.filter); len(r) > 0 {
+				e.filter = string(r[:len(r)-1])
+				e.refresh()
+			}
+		case "ctrl-c":
+			e.quit = true
+		case "up", "down", "pgup", "pgdn":
+			e.navigate(key)
+		default:
+			if len([]rune(key)) == 1 {
+				e.filter += key
+				e.refresh()
+			}
+		}
+		return
+	}
+
+	switch key {
+	case "q", "ctrl-c":
+		e.quit = true
+	case "/":
+		e.typing = true
+	case "esc":
+		e.filter = ""
+		e.refresh()
+	case "n":
+		e.nextError(true)
+	case "N":
+		e.nextError(false)
+	case "enter", " ":
+		if n := e.selected(); n != nil && len(n.children) > 0 {
+			e.collapsed[n] = !e.collapsed[n]
+			e.refresh()
+		}
+	case "right", "l":
+		n := e.selected()
+		if n == nil || len(n.children) == 0 {
+			return
+		}
+		if e.collapsed[n] {
+			delete(e.collapsed, n)
+			e.refresh()
+		} else {
+			e.move(1)
+		}
+	case "left", "h":
+		n := e.selected()
+		if n == nil {
+			return
+		}
+		if len(n.children) > 0 && !e.collapsed[n] && e.filter == "" {
+			e.collapsed[n] = true
+			e.refresh()
+		} else if p := e.parents[n]; p != nil {
+			e.selectNode(p)
+		}
+	default:
+		e.navigate(key)
+	}
+}
+
+func (e *explorer) navigate(key string) {
+	switch key {
+	case "up", "k":
+		e.move(-1)
+	case "down", "j":
+		e.move(1)
+	case "pgup":
+		e.move(-10)
+	case "pgdn":
+		e.move(10)
+	case "home", "g":
+		e.cursor = 0
+	case "end", "G":
+		e.move(len(e.rows))
+	}
+}
+
+// view renders the explorer into lines of at most width columns: the tree
+// on the left, details of the selected span on the right when there is room,
+// and a status line at the bottom.
+func (e *explorer) view(width, height int) []string {
+	bodyHeight := max(height-1, 1)
+	if e.cursor < e.top {
+		e.top = e.cursor
+	}
+	if e.cursor >= e.top+bodyHeight {
+		e.top = e.cursor - bodyHeight + 1
+	}
+
+	treeWidth := width
+	var details []string
+	if width >= 60 {
+		treeWidth = width * 3 / 5
+		details = e.details()
+	}
+
+	lines := make([]string, 0, height)
+	for i := 0; i < bodyHeight; i++ {
+		var left string
+		if idx := e.top + i; idx < len(e.rows) {
+			left = fit(e.rowText(e.rows[idx]), treeWidth)
+			if idx == e.cursor {
+				left = "\033[7m" + left + "\033[0m"
+			}
+		} else {
+			left = fit("", treeWidth)
+		}
+		if width < 60 {
+			lines = append(lines, left)
+			continue
+		}
+		right := ""
+		if i < len(details) {
+			right = details[i]
+		}
+		lines = append(lines, left+"│ "+fit(right, width-treeWidth-2))
+	}
+
+	lines = append(lines, fit(e.status(), width))
+	return lines
+}
+
+func (e *explorer) rowText(r treeRow) string {
+	n := r.node
+	marker := "  "
+	if len(n.children) > 0 {
+		marker = "▾ "
+		if e.collapsed[n] && e.filter == "" {
+			marker = "▸ "
+		}
+	}
+	errMark := ""
+	if n.hasError() {
+		errMark = "! "
+	}
+	return fmt.Sprintf("%s%s%s%s [%s] %s",
+		strings.Repeat("  ", r.depth), marker, errMark,
+		n.span.OperationName, n.service, formatDuration(n.span.Duration))
+}
+
+func (e *explorer) details() []string {
+	n := e.selected()
+	if n == nil {
+		return []string{"no spans"}
+	}
+
+	lines := []string{
+		n.span.OperationName,
+		"service:  " + n.service,
+		"span:     " + n.span.SpanID,
+		"start:    +" + formatDuration(n.span.StartTime-e.startTime),
+		"duration: " + formatDuration(n.span.Duration),
+		"self:     " + formatDuration(n.self),
+	}
+	if n.hasError() {
+		lines = append(lines, "error:    true")
+	}
+	if len(n.span.Tags) > 0 {
+		lines = append(lines, "", "tags:")
+		for _, t := range n.span.Tags {
+			lines = append(lines, detailLines("  ", t.Key, t.Value)...)
+		}
+	}
+	if len(n.span.Logs) > 0 {
+		lines = append(lines, "", "logs:")
+		for _, l := range n.span.Logs {
+			lines = append(lines, "  +"+formatDuration(l.Timestamp-e.startTime))
+			for _, f := range l.Fields {
+				lines = append(lines, detailLines("    ", f.Key, f.Value)...)
+			}
+		}
+	}
+	return lines
+}
+
+// detailLines formats a tag or log field for the details pane. Multi-line
+// values such as stack traces continue on lines of their own, indented under
+// the key.
+func detailLines(indent, key string, value any) []string {
+	values := strings.Split(strings.ReplaceAll(fmt.Sprint(value), "\r\n", "\n"), "\n")
+	lines := []string{fmt.Sprintf("%s%s = %s", indent, key, values[0])}
+	for _, v := range values[1:] {
+		lines = append(lines, indent+"  "+v)
+	}
+	return lines
+}
+
+func (e *explorer) status() string {
+	if e.typing {
+		return fmt.Sprintf("/%s█  (%d spans)", e.filter, len(e.rows))
+	}
+	help := "↑↓ move  ←→ collapse/expand  / filter  n/N next/prev error  q quit"
+	if e.filter != "" {
+		return fmt.Sprintf("filter %q (%d spans, esc to clear)  %s", e.filter, len(e.rows), help)
+	}
+	return help
+}
+
+// fit truncates or pads s with spaces to exactly width runes. Tabs become
+// spaces and other control characters are replaced, as the terminal would
+// otherwise move the cursor and break the layout.
+func fit(s string, width int) string {
+	s = strings.ReplaceAll(s, "\t", "    ")
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return '\uFFFD'
+		}
+		return r
+	}, s)
+	r := []rune(s)
+	if len(r) > width {
+		return string(r[:max(width, 0)])
+	}
+	return s + strings.Repeat(" ", width-len(r))
+}
+
+// parseKeys splits raw terminal input into key names. Printable characters
+// are returned as themselves. Input arrives in fixed-size reads, so a
+// multi-byte character can be split across two of them: an incomplete
+// character at the end is returned as rest, to be prepended to the next read.
+func parseKeys(b []byte) (keys []string, rest []byte) {
+	sequences := map[string]string{
+		"\033[A": "up", "\033[B": "down", "\033[C": "right", "\033[D": "left",
+		"\033[5~": "pgup", "\033[6~": "pgdn", "\033[H": "home", "\033[F": "end",
+	}
+
+	s := string(b)
+	for len(s) > 0 {
+		matched := false
+		for seq, name := range sequences {
+			if strings.HasPrefix(s, seq) {
+				keys = append(keys, name)
+				s = s[len(seq):]
+				matched = true
+				break
+			}
+		}
+		if matched {
+			continue
+		}
+
+		if !utf8.FullRuneInString(s) {
+			return keys, []byte(s)
+		}
+		// Invalid bytes are dropped.
+		r, size := utf8.DecodeRuneInString(s)
+		s = s[size:]
+		if r == utf8.RuneError && size == 1 {
+			continue
+		}
+		switch r {
+		case '\r', '\n':
+			keys = append(keys, "enter")
+		case 127, '\b':
+			keys = append(keys, "backspace")
+		case 3:
+			keys = append(keys, "ctrl-c")
+		case 27:
+			keys = append(keys, "esc")
+		default:
+			if r >= ' ' {
+				keys = append(keys, string(r))
+			}
+		}
+	}
+	return keys, nil
+}
+
+// runExplorer shows the interactive explorer on the terminal until the user
+// quits.
+func runExplorer(roots []*spanNode, startTime int64) error {
+	in, out := int(os.Stdin.Fd()), int(os.Stdout.Fd())
+	if !term.IsTerminal(in) || !term.IsTerminal(out) {
+		return fmt.Errorf("-i requires an interactive terminal")
+	}
+
+	state, err := term.MakeRaw(in)
+	if err != nil {
+		return fmt.Errorf("failed to enter raw mode: %w", err)
+	}
+	defer term.Restore(in, state)
+
+	// Use the alternate screen so the shell is left untouched on exit.
+	fmt.Print("\033[?1049h\033[?25l")
+	defer fmt.Print("\033[?25h\033[?1049l")
+
+	e := newExplorer(roots, startTime)
+	buf := make([]byte, 64)
+	var pending []byte
+	for !e.quit {
+		width, height, err := term.GetSize(out)
+		if err != nil {
+			width, height = 80, 24
+		}
+		lines := e.view(width, height)
+		fmt.Print("\033[H" + strings.Join(lines, "\033[K\r\n") + "\033[K\033[J")
+
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			return err
+		}
+		keys, rest := parseKeys(append(pending, buf[:n]...))
+		pending = rest
+		for _, key := range keys {
+			e.handleKey(key)
+		}
+	}
+	return nil
+}
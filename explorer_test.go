@@ -0,0 +1,278 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func explorerFixture() ([]*spanNode, int64) {
+	tr := trace{
+		Spans: []span{
+			{SpanID: "root", OperationName: "GET /checkout", StartTime: 1000, Duration: 10_000, ProcessID: "p1"},
+			{
+				SpanID:        "auth",
+				OperationName: "auth",
+				References:    []reference{{RefType: "CHILD_OF", SpanID: "root"}},
+				StartTime:     1100,
+				Duration:      500,
+				ProcessID:     "p1",
+			},
+			{
+				SpanID:        "charge",
+				OperationName: "charge",
+				References:    []reference{{RefType: "CHILD_OF", SpanID: "root"}},
+				StartTime:     2000,
+				Duration:      5_000,
+				ProcessID:     "p2",
+			},
+			{
+				SpanID:        "query",
+				OperationName: "db.query",
+				References:    []reference{{RefType: "CHILD_OF", SpanID: "charge"}},
+				StartTime:     2100,
+				Duration:      1_000,
+				ProcessID:     "p3",
+				Tags:          []tag{{Key: "error", Value: true}},
+				Logs: []spanLog{{
+					Timestamp: 2500,
+					Fields:    []tag{{Key: "event", Value: "deadlock"}},
+				}},
+			},
+		},
+		Processes: map[string]process{
+			"p1": {ServiceName: "api"},
+			"p2": {ServiceName: "payments"},
+			"p3": {ServiceName: "postgres"},
+		},
+	}
+	roots, startTime := buildTree(tr)
+	computeSelfTimes(roots)
+	return roots, startTime
+}
+
+func visibleIDs(e *explorer) []string {
+	ids := make([]string, len(e.rows))
+	for i, r := range e.rows {
+		ids[i] = r.node.span.SpanID
+	}
+	return ids
+}
+
+func TestExplorer_Navigation(t *testing.T) {
+	e := newExplorer(explorerFixture())
+
+	if got, want := visibleIDs(e), []string{"root", "auth", "charge", "query"}; !slices.Equal(got, want) {
+		t.Fatalf("rows = %v, want %v", got, want)
+	}
+
+	steps := []struct {
+		key      string
+		selected string
+		rows     []string
+	}{
+		{key: "down", selected: "auth"},
+		{key: "j", selected: "charge"},
+		{key: "left", selected: "charge", rows: []string{"root", "auth", "charge"}},
+		{key: "down", selected: "charge"},
+		{key: "right", selected: "charge", rows: []string{"root", "auth", "charge", "query"}},
+		{key: "right", selected: "query"},
+		{key: "left", selected: "charge"},
+		{key: "home", selected: "root"},
+		{key: "enter", selected: "root", rows: []string{"root"}},
+		{key: "enter", selected: "root", rows: []string{"root", "auth", "charge", "query"}},
+		{key: "end", selected: "query"},
+		{key: "up", selected: "charge"},
+	}
+
+	for i, step := range steps {
+		e.handleKey(step.key)
+		if got := e.selected().span.SpanID; got != step.selected {
+			t.Fatalf("step %d (%s): selected %s, want %s", i, step.key, got, step.selected)
+		}
+		if step.rows != nil && !slices.Equal(visibleIDs(e), step.rows) {
+			t.Fatalf("step %d (%s): rows %v, want %v", i, step.key, visibleIDs(e), step.rows)
+		}
+	}
+
+	e.handleKey("q")
+	if !e.quit {
+		t.Error("expected q to quit")
+	}
+}
+
+func TestExplorer_Filter(t *testing.T) {
+	e := newExplorer(explorerFixture())
+
+	for _, key := range []string{"/", "P", "o", "s", "t"} {
+		e.handleKey(key)
+	}
+	if !e.typing || e.filter != "Post" {
+		t.Fatalf("typing = %v, filter = %q", e.typing, e.filter)
+	}
+	if got, want := visibleIDs(e), []string{"root", "charge", "query"}; !slices.Equal(got, want) {
+		t.Errorf("filtered rows = %v, want %v", got, want)
+	}
+
+	// q is part of the filter while typing, not quit
+	e.handleKey("q")
+	if e.quit || e.filter != "Postq" || len(e.rows) != 0 {
+		t.Errorf("quit = %v, filter = %q, rows = %d", e.quit, e.filter, len(e.rows))
+	}
+	e.handleKey("backspace")
+	e.handleKey("enter")
+	if e.typing || len(e.rows) != 3 {
+		t.Errorf("typing = %v, rows = %d", e.typing, len(e.rows))
+	}
+
+	e.handleKey("esc")
+	if e.filter != "" || len(e.rows) != 4 {
+		t.Errorf("expected esc to clear the filter, got %q with %d rows", e.filter, len(e.rows))
+	}
+}
+
+func TestExplorer_NextError(t *testing.T) {
+	e := newExplorer(explorerFixture())
+
+	// collapse everything so the error is hidden
+	e.handleKey("enter")
+	if len(e.rows) != 1 {
+		t.Fatalf("expected collapsed root, got %d rows", len(e.rows))
+	}
+
+	e.handleKey("n")
+	if got := e.selected().span.SpanID; got != "query" {
+		t.Errorf("selected %s, want query", got)
+	}
+	if len(e.rows) != 4 {
+		t.Errorf("expected ancestors of the error to be expanded, got %d rows", len(e.rows))
+	}
+
+	// wraps around to the only error
+	e.handleKey("N")
+	if got := e.selected().span.SpanID; got != "query" {
+		t.Errorf("selected %s, want query", got)
+	}
+}
+
+func TestExplorer_View(t *testing.T) {
+	e := newExplorer(explorerFixture())
+	e.handleKey("end")
+
+	lines := e.view(100, 20)
+	if len(lines) != 20 {
+		t.Fatalf("view() returned %d lines, want 20", len(lines))
+	}
+	out := strings.Join(lines, "\n")
+	for _, want := range []string{
+		"▾ GET /checkout [api] 10.00ms",
+		"    ! db.query [postgres] 1.00ms",
+		"service:  postgres",
+		"start:    +1.10ms",
+		"error = true",
+		"event = deadlock",
+		"q quit",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("view() missing %q:\n%s", want, out)
+		}
+	}
+
+	narrow := e.view(40, 3)
+	if strings.Contains(strings.Join(narrow, "\n"), "service:") {
+		t.Error("expected no details pane on a narrow terminal")
+	}
+	if !strings.Contains(narrow[1], "db.query") {
+		t.Errorf("expected view to scroll to the selected span, got %q", narrow)
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    []byte
+		want     []string
+		wantRest []byte
+	}{
+		{
+			name:  "keys and escape sequences",
+			input: []byte("\033[Aj\r\033\x7f/é\033[6~\x03"),
+			want:  []string{"up", "j", "enter", "esc", "backspace", "/", "é", "pgdn", "ctrl-c"},
+		},
+		{
+			name:     "truncated utf-8 sequence",
+			input:    []byte{'j', 0xc3},
+			want:     []string{"j"},
+			wantRest: []byte{0xc3},
+		},
+		{
+			name:  "invalid byte between keys",
+			input: []byte{'j', 0xff, 'k'},
+			want:  []string{"j", "k"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, rest := parseKeys(tt.input)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("parseKeys() = %v, want %v", got, tt.want)
+			}
+			if !slices.Equal(rest, tt.wantRest) {
+				t.Errorf("parseKeys() rest = %v, want %v", rest, tt.wantRest)
+			}
+		})
+	}
+}
+
+func TestParseKeys_SplitAcrossReads(t *testing.T) {
+	input := []byte("aé")
+	keys, rest := parseKeys(input[:2])
+	if !slices.Equal(keys, []string{"a"}) {
+		t.Fatalf("first read keys = %v, want [a]", keys)
+	}
+	keys, rest = parseKeys(append(rest, input[2:]...))
+	if !slices.Equal(keys, []string{"é"}) || len(rest) != 0 {
+		t.Errorf("second read = %v, rest %v, want [é]", keys, rest)
+	}
+}
+
+func TestFit(t *testing.T) {
+	if got := fit("héllo", 3); got != "hél" {
+		t.Errorf("fit() = %q, want %q", got, "hél")
+	}
+	if got := fit("ab", 4); got != "ab  " {
+		t.Errorf("fit() = %q, want %q", got, "ab  ")
+	}
+	if got := fit("a\tb\rc", 8); got != "a    b\uFFFDc" {
+		t.Errorf("fit() = %q, want %q", got, "a    b\uFFFDc")
+	}
+}
+
+func TestExplorer_MultiLineTag(t *testing.T) {
+	root := &spanNode{
+		span: span{
+			SpanID: "root", OperationName: "GET /", StartTime: 1000, Duration: 100,
+			Tags: []tag{{Key: "exception.stacktrace", Value: "panic: boom\n\tmain.go:10\r\n\tmain.go:20"}},
+		},
+		service: "api",
+	}
+	e := newExplorer([]*spanNode{root}, 1000)
+
+	lines := e.view(80, 24)
+	if len(lines) != 24 {
+		t.Fatalf("view() returned %d lines, want 24", len(lines))
+	}
+	for _, line := range lines {
+		if strings.ContainsAny(line, "\n\r\t") {
+			t.Errorf("view() line contains control characters: %q", line)
+		}
+	}
+
+	details := strings.Join(e.details(), "\n")
+	for _, want := range []string{"  exception.stacktrace = panic: boom", "    \tmain.go:10\n", "    \tmain.go:20"} {
+		if !strings.Contains(details, want) {
+			t.Errorf("details() missing %q in\n%s", want, details)
+		}
+	}
+}
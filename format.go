@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// jsonTrace is the document written by -format json. Its fields are part of
+// the documented output schema, so only add to it.
+type jsonTrace struct {
+	TraceID    string      `json:"trace_id"`
+	StartTime  string      `json:"start_time"`
+	StartUs    int64       `json:"start_us"`
+	DurationUs int64       `json:"duration_us"`
+	SpanCount  int         `json:"span_count"`
+	Services   []string    `json:"services"`
+	Spans      []*jsonSpan `json:"spans"`
+}
+
+type jsonSpan struct {
+	SpanID     string         `json:"span_id"`
+	ParentID   string         `json:"parent_id,omitempty"`
+	Operation  string         `json:"operation"`
+	Service    string         `json:"service"`
+	StartUs    int64          `json:"start_us"`
+	OffsetUs   int64          `json:"offset_us"`
+	DurationUs int64          `json:"duration_us"`
+	SelfUs     int64          `json:"self_us"`
+	Error      bool           `json:"error"`
+	Critical   bool           `json:"critical"`
+	Matched    *bool          `json:"matched,omitempty"`
+	Tags       map[string]any `json:"tags"`
+	Logs       []jsonLog      `json:"logs,omitempty"`
+	Children   []*jsonSpan    `json:"children"`
+}
+
+type jsonLog struct {
+	TimestampUs int64          `json:"timestamp_us"`
+	OffsetUs    int64          `json:"offset_us"`
+	Fields      map[string]any `json:"fields"`
+}
+
+// newJSONTrace converts the tree to the -format json document, keeping the
+// spans that pass the filters and their ancestors, down to -depth.
+func newJSONTrace(t trace, roots []*spanNode, startTime int64, cfg *config) jsonTrace {
+	services := make(map[string]bool)
+	for _, s := range t.Spans {
+		if p, ok := t.Processes[s.ProcessID]; ok {
+			services[p.ServiceName] = true
+		}
+	}
+
+	out := jsonTrace{
+		TraceID:    t.TraceID,
+		StartTime:  time.UnixMicro(startTime).UTC().Format(time.RFC3339Nano),
+		StartUs:    startTime,
+		DurationUs: traceDuration(roots, startTime),
+		SpanCount:  len(t.Spans),
+		Services:   make([]string, 0, len(services)),
+		Spans:      jsonSpans(roots, "", 0, startTime, cfg),
+	}
+	for s := range services {
+		out.Services = append(out.Services, s)
+	}
+	sort.Strings(out.Services)
+	return out
+}
+
+func jsonSpans(nodes []*spanNode, parentID string, depth int, startTime int64, cfg *config) []*jsonSpan {
+	spans := []*jsonSpan{}
+	if cfg.maxDepth > 0 && depth >= cfg.maxDepth {
+		return spans
+	}
+	for _, n := range nodes {
+		if !cfg.highlight && !n.matchesFilter(cfg) {
+			continue
+		}
+		s := &jsonSpan{
+			SpanID:     n.span.SpanID,
+			ParentID:   parentID,
+			Operation:  n.span.OperationName,
+			Service:    n.service,
+			StartUs:    n.span.StartTime,
+			OffsetUs:   n.span.StartTime - startTime,
+			DurationUs: n.span.Duration,
+			SelfUs:     n.self,
+			Error:      n.hasError(),
+			Critical:   n.critical,
+			Tags:       tagMap(n.span.Tags),
+			Children:   jsonSpans(n.children, n.span.SpanID, depth+1, startTime, cfg),
+		}
+		if cfg.highlight {
+			matched := n.matchesSelf(cfg)
+			s.Matched = &matched
+		}
+		for _, l := range n.span.Logs {
+			s.Logs = append(s.Logs, jsonLog{
+				TimestampUs: l.Timestamp,
+				OffsetUs:    l.Timestamp - startTime,
+				Fields:      tagMap(l.Fields),
+			})
+		}
+		spans = append(spans, s)
+	}
+	return spans
+}
+
+// ndjsonSpan is one line of -format ndjson output.
+type ndjsonSpan struct {
+	TraceID    string         `json:"trace_id"`
+	SpanID     string         `json:"span_id"`
+	ParentID   string         `json:"parent_id,omitempty"`
+	Depth      int            `json:"depth"`
+	Path       []string       `json:"path"`
+	Operation  string         `json:"operation"`
+	Service    string         `json:"service"`
+	StartUs    int64          `json:"start_us"`
+	OffsetUs   int64          `json:"offset_us"`
+	DurationUs int64          `json:"duration_us"`
+	SelfUs     int64          `json:"self_us"`
+	Error      bool           `json:"error"`
+	Critical   bool           `json:"critical"`
+	Matched    *bool          `json:"matched,omitempty"`
+	Tags       map[string]any `json:"tags"`
+}
+
+// printNDJSON writes one JSON object per span, parents before their
+// children, keeping the same spans as -format json.
+func printNDJSON(w io.Writer, t trace, roots []*spanNode, startTime int64, cfg *config) error {
+	enc := json.NewEncoder(w)
+
+	var walk func(nodes []*spanNode, parentID string, path []string) error
+	walk = func(nodes []*spanNode, parentID string, path []string) error {
+		if cfg.maxDepth > 0 && len(path) >= cfg.maxDepth {
+			return nil
+		}
+		for _, n := range nodes {
+			if !cfg.highlight && !n.matchesFilter(cfg) {
+				continue
+			}
+			s := ndjsonSpan{
+				TraceID:    t.TraceID,
+				SpanID:     n.span.SpanID,
+				ParentID:   parentID,
+				Depth:      len(path),
+				Path:       path,
+				Operation:  n.span.OperationName,
+				Service:    n.service,
+				StartUs:    n.span.StartTime,
+				OffsetUs:   n.span.StartTime - startTime,
+				DurationUs: n.span.Duration,
+				SelfUs:     n.self,
+				Error:      n.hasError(),
+				Critical:   n.critical,
+				Tags:       tagMap(n.span.Tags),
+			}
+			if cfg.highlight {
+				matched := n.matchesSelf(cfg)
+				s.Matched = &matched
+			}
+			if err := enc.Encode(s); err != nil {
+				return fmt.Errorf("failed to encode span %s: %w", n.span.SpanID, err)
+			}
+			label := fmt.Sprintf("%s [%s]", n.span.OperationName, n.service)
+			if err := walk(n.children, n.span.SpanID, append(path[:len(path):len(path)], label)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return walk(roots, "", []string{})
+}
+
+// tagMap returns the tags keyed by name. A tag repeated on one span keeps its
+// last value.
+func tagMap(tags []tag) map[string]any {
+	m := make(map[string]any, len(tags))
+	for _, t := range tags {
+		m[t.Key] = t.Value
+	}
+	return m
+}
+
+func printJSONTrace(w io.Writer, t trace, roots []*spanNode, startTime int64, cfg *config) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(newJSONTrace(t, roots, startTime, cfg)); err != nil {
+		return fmt.Errorf("failed to encode trace: %w", err)
+	}
+	return nil
+}
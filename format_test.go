@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPrintJSONTrace(t *testing.T) {
+	tr := trace{
+		TraceID: "trace1",
+		Spans: []span{
+			{SpanID: "root", OperationName: "GET /", StartTime: 1000, Duration: 1000, ProcessID: "p1"},
+			{
+				SpanID: "a", OperationName: "query", StartTime: 1100, Duration: 300, ProcessID: "p2",
+				References: []reference{{RefType: "CHILD_OF", SpanID: "root"}},
+				Tags:       []tag{{Key: "error", Value: true}, {Key: "db.statement", Value: "SELECT 1"}},
+				Logs:       []spanLog{{Timestamp: 1200, Fields: []tag{{Key: "event", Value: "error"}}}},
+			},
+			{
+				SpanID: "b", OperationName: "cache.get", StartTime: 1500, Duration: 200, ProcessID: "p1",
+				References: []reference{{RefType: "CHILD_OF", SpanID: "root"}},
+			},
+		},
+		Processes: map[string]process{
+			"p1": {ServiceName: "api"},
+			"p2": {ServiceName: "postgres"},
+		},
+	}
+
+	t.Run("full tree", func(t *testing.T) {
+		var buf bytes.Buffer
+		render(&buf, tr, &config{format: "json"})
+
+		var got jsonTrace
+		if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+			t.Fatalf("output is not valid JSON: %v\n%s", err, buf.String())
+		}
+		if got.TraceID != "trace1" || got.StartUs != 1000 || got.DurationUs != 1000 || got.SpanCount != 3 {
+			t.Errorf("trace metadata = %+v", got)
+		}
+		if want := []string{"api", "postgres"}; !reflect.DeepEqual(got.Services, want) {
+			t.Errorf("services = %v, want %v", got.Services, want)
+		}
+		if len(got.Spans) != 1 || len(got.Spans[0].Children) != 2 {
+			t.Fatalf("unexpected tree shape: %s", buf.String())
+		}
+
+		root := got.Spans[0]
+		if root.SelfUs != 500 || root.ParentID != "" || !root.Critical {
+			t.Errorf("root = %+v", root)
+		}
+		query := root.Children[0]
+		if query.ParentID != "root" || query.Service != "postgres" || query.OffsetUs != 100 || !query.Error {
+			t.Errorf("query = %+v", query)
+		}
+		if query.Tags["db.statement"] != "SELECT 1" {
+			t.Errorf("query tags = %v", query.Tags)
+		}
+		if len(query.Logs) != 1 || query.Logs[0].OffsetUs != 200 || query.Logs[0].Fields["event"] != "error" {
+			t.Errorf("query logs = %+v", query.Logs)
+		}
+		if query.Children == nil {
+			t.Errorf("leaf children should be an empty array, not null")
+		}
+	})
+
+	t.Run("filters prune spans", func(t *testing.T) {
+		var buf bytes.Buffer
+		render(&buf, tr, &config{format: "json", errorsOnly: true})
+
+		var got jsonTrace
+		if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+			t.Fatalf("output is not valid JSON: %v", err)
+		}
+		if len(got.Spans) != 1 || len(got.Spans[0].Children) != 1 || got.Spans[0].Children[0].SpanID != "a" {
+			t.Errorf("unexpected tree: %s", buf.String())
+		}
+		if got.SpanCount != 3 {
+			t.Errorf("span_count = %d, want the whole trace", got.SpanCount)
+		}
+	})
+
+	t.Run("highlight marks matches", func(t *testing.T) {
+		var buf bytes.Buffer
+		render(&buf, tr, &config{format: "json", errorsOnly: true, highlight: true})
+
+		var got jsonTrace
+		if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+			t.Fatalf("output is not valid JSON: %v", err)
+		}
+		children := got.Spans[0].Children
+		if len(children) != 2 || !*children[0].Matched || *children[1].Matched {
+			t.Errorf("unexpected tree: %s", buf.String())
+		}
+	})
+}
+
+func TestPrintNDJSON(t *testing.T) {
+	tr := trace{
+		TraceID: "trace1",
+		Spans: []span{
+			{SpanID: "root", OperationName: "GET /", StartTime: 1000, Duration: 1000, ProcessID: "p1"},
+			{
+				SpanID: "a", OperationName: "query", StartTime: 1100, Duration: 300, ProcessID: "p2",
+				References: []reference{{RefType: "CHILD_OF", SpanID: "root"}},
+				Tags:       []tag{{Key: "error", Value: true}},
+			},
+			{
+				SpanID: "a1", OperationName: "fetch", StartTime: 1150, Duration: 100, ProcessID: "p2",
+				References: []reference{{RefType: "CHILD_OF", SpanID: "a"}},
+			},
+			{
+				SpanID: "b", OperationName: "cache.get", StartTime: 1500, Duration: 200, ProcessID: "p1",
+				References: []reference{{RefType: "CHILD_OF", SpanID: "root"}},
+			},
+		},
+		Processes: map[string]process{
+			"p1": {ServiceName: "api"},
+			"p2": {ServiceName: "postgres"},
+		},
+	}
+
+	decode := func(t *testing.T, cfg *config) []ndjsonSpan {
+		t.Helper()
+		var buf bytes.Buffer
+		render(&buf, tr, cfg)
+		var spans []ndjsonSpan
+		for _, line := range bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n")) {
+			var s ndjsonSpan
+			if err := json.Unmarshal(line, &s); err != nil {
+				t.Fatalf("line %q is not valid JSON: %v", line, err)
+			}
+			spans = append(spans, s)
+		}
+		return spans
+	}
+
+	t.Run("one object per span", func(t *testing.T) {
+		spans := decode(t, &config{format: "ndjson"})
+
+		var ids []string
+		for _, s := range spans {
+			ids = append(ids, s.SpanID)
+		}
+		if want := []string{"root", "a", "a1", "b"}; !reflect.DeepEqual(ids, want) {
+			t.Fatalf("span order = %v, want %v", ids, want)
+		}
+
+		a1 := spans[2]
+		if a1.TraceID != "trace1" || a1.ParentID != "a" || a1.Depth != 2 || a1.OffsetUs != 150 || a1.Service != "postgres" {
+			t.Errorf("a1 = %+v", a1)
+		}
+		if want := []string{"GET / [api]", "query [postgres]"}; !reflect.DeepEqual(a1.Path, want) {
+			t.Errorf("a1 path = %v, want %v", a1.Path, want)
+		}
+		if spans[0].Path == nil || len(spans[0].Path) != 0 || spans[0].SelfUs != 500 {
+			t.Errorf("root = %+v", spans[0])
+		}
+		if !spans[1].Error || spans[1].Tags["error"] != true {
+			t.Errorf("a = %+v", spans[1])
+		}
+	})
+
+	t.Run("filters and depth apply", func(t *testing.T) {
+		spans := decode(t, &config{format: "ndjson", errorsOnly: true, maxDepth: 2})
+
+		var ids []string
+		for _, s := range spans {
+			ids = append(ids, s.SpanID)
+		}
+		if want := []string{"root", "a"}; !reflect.DeepEqual(ids, want) {
+			t.Errorf("span ids = %v, want %v", ids, want)
+		}
+	})
+}
+
+func TestRender_JSONEncodeError(t *testing.T) {
+	tr := trace{
+		TraceID: "trace1",
+		Spans: []span{
+			{SpanID: "root", OperationName: "GET /", StartTime: 1000, Duration: 1000, Tags: []tag{{Key: "ratio", Value: math.NaN()}}},
+		},
+	}
+
+	for _, format := range []string{"json", "ndjson"} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := render(&buf, tr, &config{format: format}); err == nil {
+				t.Errorf("render() error = nil, want encode error")
+			}
+			if strings.Contains(buf.String(), "failed") {
+				t.Errorf("error written to the output: %q", buf.String())
+			}
+		})
+	}
+}
+
+func TestReportMode(t *testing.T) {
+	tests := []struct {
+		cfg  *config
+		want string
+	}{
+		{cfg: &config{}, want: ""},
+		{cfg: &config{concurrency: "annotate"}, want: ""},
+		{cfg: &config{summary: true}, want: "-summary"},
+		{cfg: &config{rootCause: true}, want: "-root-cause"},
+		{cfg: &config{concurrency: "report"}, want: "-concurrency report"},
+		{cfg: &config{repeats: 10}, want: "-repeats"},
+		{cfg: &config{top: 5}, want: "-top"},
+	}
+
+	for _, tt := range tests {
+		if got := reportMode(tt.cfg); got != tt.want {
+			t.Errorf("reportMode(%+v) = %q, want %q", *tt.cfg, got, tt.want)
+		}
+	}
+}
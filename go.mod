@@ -1,3 +1,10 @@
 module github.com/tomarrell/jtree
 
 go 1.25.3
+
+require (
+	golang.org/x/term v0.45.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require golang.org/x/sys v0.47.0 // indirect
@@ -1,12 +1,17 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"math"
 	"net/http"
 	"net/url"
 	"os"
+	"os/signal"
 	"sort"
 	"strings"
 	"time"
@@ -22,6 +27,37 @@ type config struct {
 	service      string
 	maxDepth     int
 	relativeTime bool
+	context      int
+	highlight    bool
+	from         timeBound
+	to           timeBound
+	windowStart  int64
+	windowEnd    int64
+	top          int
+	topBy        string
+	minSelf      time.Duration
+	sortBy       string
+	criticalPath bool
+	summary      bool
+	repeats      int
+	collapse     int
+	gaps         time.Duration
+	concurrency  string
+	rootCause    bool
+	wait         time.Duration
+	waitQuiet    time.Duration
+	watch        bool
+	watchEvery   time.Duration
+	watchIdle    time.Duration
+	newSpans     map[string]bool
+	interactive  bool
+	color        string
+	guides       string
+	waterfall    bool
+	format       string
+	barWidth     int
+	useColor     bool
+	traceLength  int64
 }
 
 type traceResponse struct {
@@ -42,6 +78,12 @@ type span struct {
 	Duration      int64       `json:"duration"`
 	ProcessID     string      `json:"processID"`
 	Tags          []tag       `json:"tags"`
+	Logs          []spanLog   `json:"logs"`
+}
+
+type spanLog struct {
+	Timestamp int64 `json:"timestamp"`
+	Fields    []tag `json:"fields"`
 }
 
 type reference struct {
@@ -61,6 +103,8 @@ type tag struct {
 type spanNode struct {
 	span     span
 	service  string
+	self     int64
+	critical bool
 	children []*spanNode
 }
 
@@ -76,16 +120,32 @@ func (n *spanNode) matchesFilter(cfg *config) bool {
 	return false
 }
 
+// filtering reports whether any filter is set, so that not every span
+// matches.
+func (cfg *config) filtering() bool {
+	return cfg.minDuration > 0 || cfg.minSelf > 0 || cfg.errorsOnly || cfg.service != "" ||
+		cfg.from.set || cfg.to.set || cfg.criticalPath
+}
+
 func (n *spanNode) matchesSelf(cfg *config) bool {
 	if cfg.minDuration > 0 && time.Duration(n.span.Duration)*time.Microsecond < cfg.minDuration {
 		return false
 	}
+	if cfg.minSelf > 0 && time.Duration(n.self)*time.Microsecond < cfg.minSelf {
+		return false
+	}
 	if cfg.errorsOnly && !n.hasError() {
 		return false
 	}
 	if cfg.service != "" && n.service != cfg.service {
 		return false
 	}
+	if !n.overlapsWindow(cfg) {
+		return false
+	}
+	if cfg.criticalPath && !n.critical {
+		return false
+	}
 	return true
 }
 
@@ -104,33 +164,178 @@ func (n *spanNode) hasError() bool {
 }
 
 func main() {
-	cfg := &config{jaegerURL: "http://localhost:16686"}
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "diff":
+			runDiff(os.Args[2:])
+			return
+		case "aggregate":
+			runAggregate(os.Args[2:])
+			return
+		case "baseline":
+			runBaseline(os.Args[2:])
+			return
+		case "deps":
+			runDeps(os.Args[2:])
+			return
+		case "check":
+			runCheck(os.Args[2:])
+			return
+		}
+	}
+
+	cfg := &config{
+		jaegerURL:  "http://localhost:16686",
+		topBy:      "duration",
+		sortBy:     "start",
+		collapse:   5,
+		waitQuiet:  2 * time.Second,
+		watchEvery: 2 * time.Second,
+		color:      "auto",
+		guides:     "auto",
+		format:     "text",
+	}
 	showVersion := false
 
 	flag.BoolVar(&showVersion, "version", false, "print version and exit")
 	flag.StringVar(&cfg.jaegerURL, "url", cfg.jaegerURL, "Jaeger URL")
-	flag.BoolVar(&cfg.jsonOutput, "json", false, "output verbose JSON with all tags")
+	flag.DurationVar(
+		&cfg.wait,
+		"wait",
+		0,
+		"poll for up to this long until the trace appears and stops growing",
+	)
+	flag.DurationVar(
+		&cfg.waitQuiet,
+		"wait-quiet",
+		cfg.waitQuiet,
+		"with -wait, how long the span count must stay unchanged",
+	)
+	flag.BoolVar(&cfg.interactive, "i", false, "explore the trace in an interactive terminal UI")
+	flag.BoolVar(&cfg.watch, "watch", false, "re-fetch and re-render the trace as new spans arrive")
+	flag.DurationVar(&cfg.watchEvery, "watch-interval", cfg.watchEvery, "with -watch, how often to re-fetch")
+	flag.DurationVar(
+		&cfg.watchIdle,
+		"watch-idle",
+		0,
+		"with -watch, stop once no new spans arrive for this long (0 = until interrupted)",
+	)
+	flag.StringVar(&cfg.color, "color", cfg.color, "colour output: always, never or auto (honours NO_COLOR)")
+	flag.StringVar(&cfg.guides, "guides", cfg.guides, "tree guides: auto (unicode in a UTF-8 locale, else ascii), unicode, ascii or none (plain indentation)")
+	flag.BoolVar(&cfg.waterfall, "waterfall", false, "show each span as a bar on the trace timeline, scaled to the terminal width")
+	flag.StringVar(&cfg.format, "format", cfg.format, "tree output format: text, json (a single nested document) or ndjson (one object per span)")
+	flag.BoolVar(&cfg.jsonOutput, "json", false, "output verbose JSON with all tags, one span per line")
 	flag.DurationVar(
 		&cfg.minDuration,
 		"min-duration",
 		0,
 		"only show spans with duration >= this value (e.g. 100ms, 1s)",
 	)
+	flag.DurationVar(
+		&cfg.minSelf,
+		"min-self",
+		0,
+		"only show spans with self time (excluding children) >= this value",
+	)
 	flag.BoolVar(&cfg.errorsOnly, "error", false, "only show error spans and their ancestors")
 	flag.StringVar(&cfg.service, "service", "", "only show spans from this service")
 	flag.IntVar(&cfg.maxDepth, "depth", 0, "limit tree depth (0 = unlimited)")
 	flag.BoolVar(&cfg.relativeTime, "relative", false, "show timestamps relative to trace start")
+	flag.Var(
+		&cfg.from,
+		"from",
+		"only show spans running after this time (+offset from trace start, or clock time)",
+	)
+	flag.Var(
+		&cfg.to,
+		"to",
+		"only show spans running before this time (+offset from trace start, or clock time)",
+	)
+	flag.BoolVar(
+		&cfg.criticalPath,
+		"critical-path",
+		false,
+		"only show spans on the critical path (combine with -highlight to mark them)",
+	)
+	flag.BoolVar(
+		&cfg.summary,
+		"summary",
+		false,
+		"print a per-service and per-operation latency breakdown instead of the tree",
+	)
+	flag.IntVar(
+		&cfg.repeats,
+		"repeats",
+		0,
+		"report sibling calls to the same operation repeated at least N times (N+1 detection)",
+	)
+	flag.BoolVar(
+		&cfg.rootCause,
+		"root-cause",
+		false,
+		"show where errors originated and the spans they propagated through",
+	)
+	flag.StringVar(
+		&cfg.concurrency,
+		"concurrency",
+		"",
+		"analyse how concurrently children ran: report, or annotate the tree",
+	)
+	flag.IntVar(&cfg.top, "top", 0, "list the N slowest spans with their ancestor path")
+	flag.StringVar(&cfg.topBy, "top-by", cfg.topBy, "rank -top spans by duration or self")
+	flag.StringVar(&cfg.sortBy, "sort", cfg.sortBy, "order siblings by start, duration or self")
+	flag.DurationVar(
+		&cfg.gaps,
+		"gaps",
+		0,
+		"show idle time of at least this duration inside a span as (gap) nodes",
+	)
+	flag.IntVar(
+		&cfg.collapse,
+		"collapse",
+		cfg.collapse,
+		"fold runs of at least N consecutive siblings with the same operation (0 = off)",
+	)
+	flag.BoolVar(
+		&cfg.highlight,
+		"highlight",
+		false,
+		"mark spans matching the filters instead of hiding the rest",
+	)
+	flag.IntVar(
+		&cfg.context,
+		"context",
+		0,
+		"show N siblings before/after each matching span, summarising the rest",
+	)
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, `jtree - display Jaeger traces in a hierarchical view
 
 Usage:
   jtree [flags] <trace-id>
+  jtree diff [flags] <trace-a> <trace-b>
+  jtree aggregate [flags] [trace-id...]
+  jtree baseline save|check [flags] [trace-id...]
+  jtree deps [flags] [trace-id...]
+  jtree check -rules <file> [flags] <trace-id>
 
 Examples:
   jtree abc123def456
   jtree http://localhost:16686/trace/abc123def456
   jtree -json abc123def456
+  jtree -format json abc123def456 | jq .spans[0].duration_us
+  jtree -format ndjson abc123def456 | jq 'select(.error) | .path'
+  jtree -summary abc123def456
+  jtree -root-cause abc123def456
+  jtree -repeats 10 abc123def456
+  jtree -concurrency report abc123def456
+  jtree -critical-path -highlight abc123def456
+  jtree -top 10 -top-by self abc123def456
+  jtree -from +2s -to +5.5s abc123def456
   jtree -url http://jaeger:16686 abc123def456
+  jtree -i abc123def456
+  jtree -wait 30s abc123def456
+  jtree -watch -watch-idle 1m abc123def456
 
 Flags:
 `)
@@ -148,35 +353,183 @@ Flags:
 		os.Exit(1)
 	}
 
+	if cfg.topBy != "duration" && cfg.topBy != "self" {
+		fmt.Fprintf(os.Stderr, "invalid -top-by %q: must be duration or self\n", cfg.topBy)
+		os.Exit(1)
+	}
+	if cfg.sortBy != "start" && cfg.sortBy != "duration" && cfg.sortBy != "self" {
+		fmt.Fprintf(os.Stderr, "invalid -sort %q: must be start, duration or self\n", cfg.sortBy)
+		os.Exit(1)
+	}
+	if cfg.gaps > 0 && cfg.sortBy != "start" {
+		// Gaps are placed between siblings by start time.
+		fmt.Fprintln(os.Stderr, "-gaps requires -sort start")
+		os.Exit(1)
+	}
+	if cfg.format != "text" && cfg.format != "json" && cfg.format != "ndjson" {
+		fmt.Fprintf(os.Stderr, "invalid -format %q: must be text, json or ndjson\n", cfg.format)
+		os.Exit(1)
+	}
+	if cfg.watch && (cfg.interactive || cfg.wait > 0) {
+		// -watch already polls until interrupted, and -i takes over the
+		// terminal.
+		fmt.Fprintln(os.Stderr, "-watch cannot be used with -i or -wait")
+		os.Exit(1)
+	}
+	if cfg.watch && cfg.format != "text" {
+		// Each refresh would repeat the document behind a status line.
+		fmt.Fprintf(os.Stderr, "-watch cannot be used with -format %s\n", cfg.format)
+		os.Exit(1)
+	}
+	if mode := reportMode(cfg); mode != "" && cfg.format != "text" {
+		// Reports are only printed as text.
+		fmt.Fprintf(os.Stderr, "%s cannot be used with -format %s\n", mode, cfg.format)
+		os.Exit(1)
+	}
+	cfg.guides = resolveGuides(cfg.guides, os.Getenv)
+	if _, ok := guideStyles[cfg.guides]; !ok {
+		fmt.Fprintf(os.Stderr, "invalid -guides %q: must be auto, unicode, ascii or none\n", cfg.guides)
+		os.Exit(1)
+	}
+	useColor, err := resolveColor(cfg.color, isTerminal(os.Stdout), os.Getenv("NO_COLOR") != "")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	cfg.useColor = useColor
+	cfg.barWidth = waterfallWidth(terminalColumns())
+	if cfg.concurrency != "" && cfg.concurrency != "report" && cfg.concurrency != "annotate" {
+		fmt.Fprintf(os.Stderr, "invalid -concurrency %q: must be report or annotate\n", cfg.concurrency)
+		os.Exit(1)
+	}
+
 	baseURL, traceID := parseInput(flag.Arg(0), cfg.jaegerURL)
 	cfg.jaegerURL = baseURL
 
-	resp, err := http.Get(fmt.Sprintf("%s/api/traces/%s", cfg.jaegerURL, traceID))
+	if cfg.watch {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		fetch := func() (trace, error) { return fetchTrace(cfg.jaegerURL, traceID) }
+		if err := watchTrace(ctx, os.Stdout, fetch, cfg, isTerminal(os.Stdout)); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	t, err := waitForTrace(cfg.jaegerURL, traceID, cfg.wait, cfg.waitQuiet, pollInterval)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "failed to fetch trace: %v\n", err)
+		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		fmt.Fprintf(os.Stderr, "jaeger returned status %d\n", resp.StatusCode)
-		os.Exit(1)
+	if cfg.interactive {
+		roots, startTime := buildTree(t)
+		computeSelfTimes(roots)
+		if err := runExplorer(roots, startTime); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
 	}
 
-	var traceResp traceResponse
-	if err := json.NewDecoder(resp.Body).Decode(&traceResp); err != nil {
-		fmt.Fprintf(os.Stderr, "failed to decode response: %v\n", err)
+	if err := render(os.Stdout, t, cfg); err != nil {
+		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
+}
 
-	if len(traceResp.Data) == 0 {
-		fmt.Fprintf(os.Stderr, "no trace found with ID %s\n", traceID)
-		os.Exit(1)
+// reportMode returns the flag selecting a report in place of the tree, if
+// any.
+func reportMode(cfg *config) string {
+	switch {
+	case cfg.summary:
+		return "-summary"
+	case cfg.rootCause:
+		return "-root-cause"
+	case cfg.concurrency == "report":
+		return "-concurrency report"
+	case cfg.repeats > 0:
+		return "-repeats"
+	case cfg.top > 0:
+		return "-top"
 	}
+	return ""
+}
 
-	t := traceResp.Data[0]
+// render prints the trace in the configured output. It fails when -from is
+// not before -to, or when a tag value cannot be encoded as JSON.
+func render(w io.Writer, t trace, cfg *config) error {
 	roots, startTime := buildTree(t)
-	printRoots(roots, startTime, cfg)
+	computeSelfTimes(roots)
+	markCriticalPath(roots)
+	if cfg.sortBy != "start" {
+		sortTree(roots, cfg.sortBy)
+	}
+	cfg.windowStart = cfg.from.resolve(startTime)
+	cfg.windowEnd = cfg.to.resolve(startTime)
+	if cfg.from.set && cfg.to.set && cfg.windowStart >= cfg.windowEnd {
+		return fmt.Errorf("-from %s is not before -to %s", &cfg.from, &cfg.to)
+	}
+	cfg.traceLength = traceDuration(roots, startTime)
+
+	switch {
+	case cfg.summary:
+		printSummary(w, roots, startTime, cfg)
+	case cfg.rootCause:
+		printRootCause(w, roots, startTime, cfg)
+	case cfg.concurrency == "report":
+		printConcurrency(w, roots, cfg)
+	case cfg.repeats > 0:
+		printRepeats(w, roots, cfg)
+	case cfg.top > 0:
+		printTop(w, roots, startTime, cfg)
+	case cfg.format == "json":
+		return printJSONTrace(w, t, roots, startTime, cfg)
+	case cfg.format == "ndjson":
+		return printNDJSON(w, t, roots, startTime, cfg)
+	default:
+		printRoots(w, roots, startTime, cfg)
+	}
+	return nil
+}
+
+var errTraceNotFound = errors.New("no trace found")
+
+func fetchTrace(baseURL, traceID string) (trace, error) {
+	traces, err := getTraces(fmt.Sprintf("%s/api/traces/%s", baseURL, traceID))
+	if err != nil {
+		return trace{}, err
+	}
+
+	if len(traces) == 0 {
+		return trace{}, fmt.Errorf("%w with ID %s", errTraceNotFound, traceID)
+	}
+
+	return traces[0], nil
+}
+
+func getTraces(u string) ([]trace, error) {
+	resp, err := http.Get(u)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch trace: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Jaeger responds 404 to traces it has not (yet) stored.
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("jaeger returned status %d", resp.StatusCode)
+	}
+
+	var traceResp traceResponse
+	if err := json.NewDecoder(resp.Body).Decode(&traceResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return traceResp.Data, nil
 }
 
 func buildTree(t trace) ([]*spanNode, int64) {
@@ -215,6 +568,21 @@ func buildTree(t trace) ([]*spanNode, int64) {
 	return roots, startTime
 }
 
+// traceDuration returns the time from the start of the trace to the end of the
+// last span.
+func traceDuration(roots []*spanNode, startTime int64) int64 {
+	var end int64
+	var walk func(nodes []*spanNode)
+	walk = func(nodes []*spanNode) {
+		for _, n := range nodes {
+			end = max(end, n.span.StartTime+n.span.Duration)
+			walk(n.children)
+		}
+	}
+	walk(roots)
+	return max(end-startTime, 0)
+}
+
 func getParentID(s span) string {
 	for _, ref := range s.References {
 		if ref.RefType == "CHILD_OF" {
@@ -230,21 +598,177 @@ func sortNodes(nodes []*spanNode) {
 	})
 }
 
-func printRoots(roots []*spanNode, startTime int64, cfg *config) {
-	for _, root := range roots {
-		printNode(root, 0, startTime, cfg)
+func printRoots(w io.Writer, roots []*spanNode, startTime int64, cfg *config) {
+	printNodes(w, roots, nil, 0, "", startTime, cfg)
+
+	if cfg.highlight {
+		matched, total := 0, 0
+		for _, root := range roots {
+			m, t := root.countMatches(cfg)
+			matched += m
+			total += t
+		}
+		fmt.Fprintf(w, "\n%d of %d spans matched\n", matched, total)
 	}
 }
 
-func printNode(node *spanNode, depth int, startTime int64, cfg *config) {
-	if !node.matchesFilter(cfg) {
-		return
+// countMatches returns the number of spans in the subtree matching the filters
+// and the total number of spans in the subtree.
+func (n *spanNode) countMatches(cfg *config) (matched, total int) {
+	if n.matchesSelf(cfg) {
+		matched++
+	}
+	total++
+	for _, child := range n.children {
+		m, t := child.countMatches(cfg)
+		matched += m
+		total += t
 	}
+	return matched, total
+}
+
+func printNodes(
+	w io.Writer,
+	nodes []*spanNode,
+	gaps []interval,
+	depth int,
+	indent string,
+	startTime int64,
+	cfg *config,
+) {
 	if cfg.maxDepth > 0 && depth >= cfg.maxDepth {
 		return
 	}
 
-	indent := strings.Repeat("  ", depth)
+	keep := make([]bool, len(nodes))
+	for i, node := range nodes {
+		keep[i] = cfg.highlight || node.matchesFilter(cfg)
+	}
+
+	context := make([]bool, len(nodes))
+	if cfg.context > 0 {
+		context = contextWindow(keep, cfg.context)
+	}
+
+	var lines []func(prefix, indent string)
+	addGaps := func(before int64) {
+		for len(gaps) > 0 && gaps[0].start < before {
+			g := gaps[0]
+			gap := fmt.Sprintf("(gap %s)", formatDuration(g.end-g.start))
+			lines = append(lines, func(prefix, _ string) {
+				_, light := cfg.barFill()
+				fmt.Fprintf(w, "%s%s%s\n",
+					cfg.bar(g.start, g.end-g.start, startTime, light, colorDim),
+					linePrefix(prefix, false, cfg),
+					paint(gap, colorDim, cfg),
+				)
+			})
+			gaps = gaps[1:]
+		}
+	}
+
+	// Lines are collected before printing so that the last one at this level,
+	// whatever the filters left, gets the closing guide.
+	for i := 0; i < len(nodes); {
+		addGaps(nodes[i].span.StartTime)
+		node := nodes[i]
+		switch {
+		case keep[i]:
+			j := repeatRun(nodes, keep, i)
+			if cfg.collapse > 0 && j-i >= cfg.collapse && foldable(nodes[i:j], cfg) {
+				run := nodes[i:j]
+				lines = append(lines, func(prefix, _ string) {
+					printCollapsed(w, run, prefix, startTime, cfg)
+				})
+				i = j
+				continue
+			}
+			lines = append(lines, func(prefix, indent string) {
+				printNode(w, node, depth, prefix, indent, startTime, cfg)
+			})
+			i++
+		case context[i]:
+			lines = append(lines, func(prefix, _ string) {
+				printSpan(w, node, prefix, startTime, cfg)
+			})
+			i++
+		default:
+			j := i
+			for j < len(nodes) && !keep[j] && !context[j] {
+				j++
+			}
+			if cfg.context > 0 {
+				count, duration := summarise(nodes[i:j])
+				more := fmt.Sprintf("… %d more spans (%s)", count, formatDuration(duration))
+				lines = append(lines, func(prefix, _ string) {
+					fmt.Fprintf(w, "%s%s%s\n", cfg.bar(0, -1, 0, "", ""), prefix, paint(more, colorDim, cfg))
+				})
+			}
+			i = j
+		}
+	}
+	addGaps(math.MaxInt64)
+
+	guides := cfg.treeGuides()
+	for i, line := range lines {
+		switch {
+		case depth == 0:
+			line(indent, indent)
+		case i == len(lines)-1:
+			line(indent+guides.last, indent+guides.space)
+		default:
+			line(indent+guides.branch, indent+guides.pipe)
+		}
+	}
+}
+
+// contextWindow marks the siblings within n positions of a kept sibling that
+// are not kept themselves. If nothing is kept there is no context to show.
+func contextWindow(keep []bool, n int) []bool {
+	context := make([]bool, len(keep))
+	for i, k := range keep {
+		if !k {
+			continue
+		}
+		for j := max(0, i-n); j <= min(len(keep)-1, i+n); j++ {
+			context[j] = !keep[j]
+		}
+	}
+	return context
+}
+
+// summarise returns the number of spans in the given subtrees and the sum of
+// the top-level span durations.
+func summarise(nodes []*spanNode) (count int, duration int64) {
+	for _, node := range nodes {
+		count += node.count()
+		duration += node.span.Duration
+	}
+	return count, duration
+}
+
+func (n *spanNode) count() int {
+	total := 1
+	for _, child := range n.children {
+		total += child.count()
+	}
+	return total
+}
+
+// printNode prints the span on a line starting with prefix, followed by its
+// children indented by indent.
+func printNode(w io.Writer, node *spanNode, depth int, prefix, indent string, startTime int64, cfg *config) {
+	printSpan(w, node, prefix, startTime, cfg)
+
+	var gaps []interval
+	if cfg.gaps > 0 {
+		gaps = node.gaps(cfg.gaps.Microseconds())
+	}
+	printNodes(w, node.children, gaps, depth+1, indent, startTime, cfg)
+}
+
+func printSpan(w io.Writer, node *spanNode, prefix string, startTime int64, cfg *config) {
+	indent := linePrefix(prefix, node.matchesSelf(cfg), cfg)
 	duration := formatDuration(node.span.Duration)
 
 	if cfg.jsonOutput {
@@ -253,27 +777,71 @@ func printNode(node *spanNode, depth int, startTime int64, cfg *config) {
 			tags[t.Key] = t.Value
 		}
 		out := map[string]any{
-			"span_id":  node.span.SpanID,
-			"service":  node.service,
-			"duration": duration,
-			"tags":     tags,
+			"span_id":   node.span.SpanID,
+			"service":   node.service,
+			"duration":  duration,
+			"self_time": formatDuration(node.self),
+			"tags":      tags,
+		}
+		if cfg.newSpans[node.span.SpanID] {
+			out["new"] = true
 		}
 		jsonBytes, _ := json.Marshal(out)
-		fmt.Printf("%s%s %s\n", indent, node.span.OperationName, string(jsonBytes))
+		fmt.Fprintf(w, "%s%s %s\n", indent, node.span.OperationName, string(jsonBytes))
+		return
+	}
+
+	var timeStr string
+	if cfg.relativeTime {
+		offset := node.span.StartTime - startTime
+		timeStr = "+" + formatDuration(offset)
 	} else {
-		var timeStr string
-		if cfg.relativeTime {
-			offset := node.span.StartTime - startTime
-			timeStr = "+" + formatDuration(offset)
-		} else {
-			timeStr = time.UnixMicro(node.span.StartTime).Format("15:04:05.000")
-		}
-		fmt.Printf("%s%s [%s] %s %s\n", indent, node.span.OperationName, node.service, timeStr, duration)
+		timeStr = time.UnixMicro(node.span.StartTime).Format("15:04:05.000")
+	}
+	operation := node.span.OperationName
+	barColor := serviceColor(node.service)
+	if node.hasError() {
+		operation = paint(operation, colorRed, cfg)
+		barColor = colorRed
+	}
+	solid, _ := cfg.barFill()
+	fmt.Fprintf(w, "%s%s%s [%s] %s %s",
+		cfg.bar(node.span.StartTime, node.span.Duration, startTime, solid, barColor),
+		indent,
+		operation,
+		paint(node.service, serviceColor(node.service), cfg),
+		paint(timeStr, colorDim, cfg),
+		paint(duration, durationColor(node.span.Duration, cfg.traceLength), cfg),
+	)
+	if len(node.children) > 0 {
+		fmt.Fprintf(w, " (self %s)", formatDuration(node.self))
+	}
+	if cfg.concurrency == "annotate" && len(node.children) >= 2 {
+		stats := node.concurrency()
+		fmt.Fprintf(w, " (concurrency max %d, avg %.2f)", stats.maxConcurrent, stats.avgConcurrent)
 	}
+	if cfg.newSpans[node.span.SpanID] {
+		fmt.Fprint(w, " (new)")
+	}
+	fmt.Fprintln(w)
+}
 
-	for _, child := range node.children {
-		printNode(child, depth+1, startTime, cfg)
+// linePrefix returns the tree guide for a line, preceded by a marker in
+// highlight mode.
+func linePrefix(prefix string, matched bool, cfg *config) string {
+	if !cfg.highlight {
+		return prefix
+	}
+	if matched {
+		return paint(">>", colorBold, cfg) + " " + prefix
 	}
+	return "   " + prefix
+}
+
+// isTerminal reports whether f is a terminal rather than a file or pipe.
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
 }
 
 func formatDuration(us int64) string {
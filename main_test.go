@@ -1,6 +1,9 @@
 package main
 
-import "testing"
+import (
+	"bytes"
+	"testing"
+)
 
 func TestParseInput(t *testing.T) {
 	tests := []struct {
@@ -311,6 +314,28 @@ func TestSpanNode_matchesSelf(t *testing.T) {
 			},
 			matches: true,
 		},
+		{
+			name: "minSelf filter - self time below threshold",
+			node: &spanNode{
+				span: span{Duration: 200_000}, // 200ms
+				self: 10_000,                  // 10ms
+			},
+			cfg: &config{
+				minSelf: 50000000, // 50ms in nanoseconds
+			},
+			matches: false,
+		},
+		{
+			name: "minSelf filter - self time above threshold",
+			node: &spanNode{
+				span: span{Duration: 200_000}, // 200ms
+				self: 150_000,                 // 150ms
+			},
+			cfg: &config{
+				minSelf: 50000000, // 50ms in nanoseconds
+			},
+			matches: true,
+		},
 		{
 			name: "errorsOnly filter - span with error",
 			node: &spanNode{
@@ -1194,3 +1219,124 @@ func TestSpanNode_matchesFilter(t *testing.T) {
 		})
 	}
 }
+
+func TestPrintRoots(t *testing.T) {
+	child := func(id string, start, duration int64, tags ...tag) span {
+		return span{
+			SpanID:        id,
+			OperationName: id,
+			References:    []reference{{RefType: "CHILD_OF", SpanID: "root"}},
+			StartTime:     1000 + start,
+			Duration:      duration,
+			ProcessID:     "p1",
+			Tags:          tags,
+		}
+	}
+	tr := trace{
+		TraceID: "trace1",
+		Spans: []span{
+			{SpanID: "root", OperationName: "root", StartTime: 1000, Duration: 10_000, ProcessID: "p1"},
+			child("a", 100, 1_000),
+			child("b", 200, 2_000),
+			child("c", 300, 100),
+			child("d", 400, 100, tag{Key: "error", Value: true}),
+			child("e", 500, 100),
+			child("f", 600, 500),
+			child("g", 700, 500),
+		},
+		Processes: map[string]process{
+			"p1": {ServiceName: "svc"},
+		},
+	}
+
+	tests := []struct {
+		name string
+		cfg  *config
+		want string
+	}{
+		{
+			name: "no context prunes siblings",
+			cfg:  &config{errorsOnly: true, relativeTime: true},
+			want: `root [svc] +0us 10.00ms (self 7.90ms)
+  d [svc] +400us 100us
+`,
+		},
+		{
+			name: "context shows neighbours and summarises the rest",
+			cfg:  &config{errorsOnly: true, relativeTime: true, context: 1},
+			want: `root [svc] +0us 10.00ms (self 7.90ms)
+  … 2 more spans (3.00ms)
+  c [svc] +300us 100us
+  d [svc] +400us 100us
+  e [svc] +500us 100us
+  … 2 more spans (1.00ms)
+`,
+		},
+		{
+			name: "highlight marks matches and keeps the tree",
+			cfg:  &config{errorsOnly: true, relativeTime: true, highlight: true},
+			want: `   root [svc] +0us 10.00ms (self 7.90ms)
+     a [svc] +100us 1.00ms
+     b [svc] +200us 2.00ms
+     c [svc] +300us 100us
+>>   d [svc] +400us 100us
+     e [svc] +500us 100us
+     f [svc] +600us 500us
+     g [svc] +700us 500us
+
+1 of 8 spans matched
+`,
+		},
+		{
+			name: "gaps are shown between children",
+			cfg:  &config{relativeTime: true, gaps: 3_000_000},
+			want: `root [svc] +0us 10.00ms (self 7.90ms)
+  a [svc] +100us 1.00ms
+  b [svc] +200us 2.00ms
+  c [svc] +300us 100us
+  d [svc] +400us 100us
+  e [svc] +500us 100us
+  f [svc] +600us 500us
+  g [svc] +700us 500us
+  (gap 7.80ms)
+`,
+		},
+		{
+			name: "context larger than sibling count",
+			cfg:  &config{errorsOnly: true, relativeTime: true, context: 10},
+			want: `root [svc] +0us 10.00ms (self 7.90ms)
+  a [svc] +100us 1.00ms
+  b [svc] +200us 2.00ms
+  c [svc] +300us 100us
+  d [svc] +400us 100us
+  e [svc] +500us 100us
+  f [svc] +600us 500us
+  g [svc] +700us 500us
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			roots, startTime := buildTree(tr)
+			computeSelfTimes(roots)
+			var buf bytes.Buffer
+			printRoots(&buf, roots, startTime, tt.cfg)
+			if got := buf.String(); got != tt.want {
+				t.Errorf("printRoots() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContextWindow(t *testing.T) {
+	keep := []bool{false, false, true, false, false, false, true}
+	want := []bool{false, true, false, true, false, true, false}
+
+	got := contextWindow(keep, 1)
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("contextWindow()[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
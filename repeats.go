@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+type repeatGroup struct {
+	parent    *spanNode
+	service   string
+	operation string
+	statement string
+	spans     []*spanNode
+}
+
+// total returns the summed duration of the repeated calls.
+func (g *repeatGroup) total() int64 {
+	var total int64
+	for _, n := range g.spans {
+		total += n.span.Duration
+	}
+	return total
+}
+
+// wall returns the time from the start of the first call to the end of the
+// last.
+func (g *repeatGroup) wall() int64 {
+	var start, end int64
+	for i, n := range g.spans {
+		if i == 0 || n.span.StartTime < start {
+			start = n.span.StartTime
+		}
+		end = max(end, n.span.StartTime+n.span.Duration)
+	}
+	return end - start
+}
+
+// findRepeats returns the groups of at least threshold sibling spans matching
+// the filters that share a service, operation and normalised db statement,
+// sorted by total time, highest first.
+func findRepeats(roots []*spanNode, threshold int, cfg *config) []*repeatGroup {
+	var groups []*repeatGroup
+
+	var walk func(parent *spanNode, nodes []*spanNode)
+	walk = func(parent *spanNode, nodes []*spanNode) {
+		byKey := make(map[[3]string]*repeatGroup)
+		var order []*repeatGroup
+		for _, n := range nodes {
+			if n.matchesSelf(cfg) {
+				stmt := normaliseStatement(n.statement())
+				key := [3]string{n.service, n.span.OperationName, stmt}
+				g, ok := byKey[key]
+				if !ok {
+					g = &repeatGroup{
+						parent:    parent,
+						service:   n.service,
+						operation: n.span.OperationName,
+						statement: stmt,
+					}
+					byKey[key] = g
+					order = append(order, g)
+				}
+				g.spans = append(g.spans, n)
+			}
+			walk(n, n.children)
+		}
+		for _, g := range order {
+			if len(g.spans) >= threshold {
+				groups = append(groups, g)
+			}
+		}
+	}
+	walk(nil, roots)
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i].total() > groups[j].total()
+	})
+	return groups
+}
+
+// statement returns the database statement recorded on the span, if any.
+func (n *spanNode) statement() string {
+	for _, t := range n.span.Tags {
+		if t.Key == "db.statement" || t.Key == "db.query.text" {
+			if s, ok := t.Value.(string); ok {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+var (
+	quotedLiteral  = regexp.MustCompile(`'(?:[^']|'')*'`)
+	numericLiteral = regexp.MustCompile(`\b\d+(?:\.\d+)?\b`)
+	placeholder    = regexp.MustCompile(`\$\d+`)
+	whitespace     = regexp.MustCompile(`\s+`)
+)
+
+// normaliseStatement replaces literals and placeholders in a statement with ?
+// so that the same query issued with different arguments groups together.
+func normaliseStatement(s string) string {
+	s = quotedLiteral.ReplaceAllString(s, "?")
+	s = placeholder.ReplaceAllString(s, "?")
+	s = numericLiteral.ReplaceAllString(s, "?")
+	s = whitespace.ReplaceAllString(s, " ")
+	return strings.TrimSpace(s)
+}
+
+// maxConcurrency returns the largest number of the spans running at once.
+func maxConcurrency(nodes []*spanNode) int {
+	type event struct {
+		at    int64
+		delta int
+	}
+	events := make([]event, 0, len(nodes)*2)
+	for _, n := range nodes {
+		events = append(events,
+			event{n.span.StartTime, 1},
+			event{n.span.StartTime + n.span.Duration, -1},
+		)
+	}
+	// Ends sort before starts at the same instant so back-to-back spans are
+	// not counted as concurrent.
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].at != events[j].at {
+			return events[i].at < events[j].at
+		}
+		return events[i].delta < events[j].delta
+	})
+
+	cur, peak := 0, 0
+	for _, e := range events {
+		cur += e.delta
+		peak = max(peak, cur)
+	}
+	return peak
+}
+
+func printRepeats(w io.Writer, roots []*spanNode, cfg *config) {
+	groups := findRepeats(roots, cfg.repeats, cfg)
+	if len(groups) == 0 {
+		fmt.Fprintf(w, "no sibling spans repeated %d or more times\n", cfg.repeats)
+		return
+	}
+
+	for _, g := range groups {
+		fmt.Fprintf(w, "%s [%s] ×%d", g.operation, g.service, len(g.spans))
+		if g.parent != nil {
+			fmt.Fprintf(w, " under %s [%s]", g.parent.span.OperationName, g.parent.service)
+		}
+		fmt.Fprintln(w)
+
+		mode := "sequential"
+		if c := maxConcurrency(g.spans); c > 1 {
+			mode = fmt.Sprintf("parallel (max %d concurrent)", c)
+		}
+		fmt.Fprintf(w, "  total %s, wall %s, %s\n", formatDuration(g.total()), formatDuration(g.wall()), mode)
+		if g.statement != "" {
+			fmt.Fprintf(w, "  %s\n", g.statement)
+		}
+	}
+}
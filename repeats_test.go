@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestNormaliseStatement(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			input:    "SELECT * FROM items WHERE id = 42",
+			expected: "SELECT * FROM items WHERE id = ?",
+		},
+		{
+			input:    "SELECT * FROM users WHERE name = 'o''brien' AND age > 3.5",
+			expected: "SELECT * FROM users WHERE name = ? AND age > ?",
+		},
+		{
+			input:    "UPDATE t2 SET a = $1 WHERE b = $2",
+			expected: "UPDATE t2 SET a = ? WHERE b = ?",
+		},
+		{
+			input:    "SELECT  *\n\tFROM items ",
+			expected: "SELECT * FROM items",
+		},
+		{
+			input:    "",
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := normaliseStatement(tt.input); got != tt.expected {
+				t.Errorf("normaliseStatement(%q) = %q, want %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestMaxConcurrency(t *testing.T) {
+	node := func(start, duration int64) *spanNode {
+		return &spanNode{span: span{StartTime: start, Duration: duration}}
+	}
+
+	tests := []struct {
+		name     string
+		nodes    []*spanNode
+		expected int
+	}{
+		{name: "empty", expected: 0},
+		{name: "single", nodes: []*spanNode{node(0, 10)}, expected: 1},
+		{name: "back to back", nodes: []*spanNode{node(0, 10), node(10, 10), node(20, 10)}, expected: 1},
+		{name: "fully parallel", nodes: []*spanNode{node(0, 10), node(0, 10), node(0, 10)}, expected: 3},
+		{name: "staggered", nodes: []*spanNode{node(0, 10), node(5, 10), node(12, 10)}, expected: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := maxConcurrency(tt.nodes); got != tt.expected {
+				t.Errorf("maxConcurrency() = %d, want %d", got, tt.expected)
+			}
+		})
+	}
+}
+
+func repeatsTrace() trace {
+	spans := []span{
+		{SpanID: "root", OperationName: "GET /orders", StartTime: 1000, Duration: 10_000, ProcessID: "p1"},
+	}
+	for i := range 4 {
+		spans = append(spans, span{
+			SpanID:        fmt.Sprintf("q%d", i),
+			OperationName: "db.query",
+			References:    []reference{{RefType: "CHILD_OF", SpanID: "root"}},
+			StartTime:     int64(2000 + i*1000),
+			Duration:      500,
+			ProcessID:     "p2",
+			Tags:          []tag{{Key: "db.statement", Value: fmt.Sprintf("SELECT * FROM items WHERE id = %d", i)}},
+		})
+	}
+	for i := range 3 {
+		spans = append(spans, span{
+			SpanID:        fmt.Sprintf("h%d", i),
+			OperationName: "GET /price",
+			References:    []reference{{RefType: "CHILD_OF", SpanID: "root"}},
+			StartTime:     7000,
+			Duration:      200,
+			ProcessID:     "p3",
+		})
+	}
+	spans = append(spans, span{
+		SpanID:        "other",
+		OperationName: "db.query",
+		References:    []reference{{RefType: "CHILD_OF", SpanID: "root"}},
+		StartTime:     9000,
+		Duration:      100,
+		ProcessID:     "p2",
+		Tags:          []tag{{Key: "db.statement", Value: "SELECT * FROM users WHERE id = 1"}},
+	})
+	return trace{
+		TraceID: "trace1",
+		Spans:   spans,
+		Processes: map[string]process{
+			"p1": {ServiceName: "api"},
+			"p2": {ServiceName: "postgres"},
+			"p3": {ServiceName: "pricing"},
+		},
+	}
+}
+
+func TestFindRepeats(t *testing.T) {
+	roots, _ := buildTree(repeatsTrace())
+
+	groups := findRepeats(roots, 3, &config{})
+	if len(groups) != 2 {
+		t.Fatalf("expected 2 groups, got %d", len(groups))
+	}
+
+	q := groups[0]
+	if q.operation != "db.query" || len(q.spans) != 4 || q.total() != 2000 || q.wall() != 3500 {
+		t.Errorf("unexpected db.query group: %s ×%d total %d wall %d", q.operation, len(q.spans), q.total(), q.wall())
+	}
+	if q.statement != "SELECT * FROM items WHERE id = ?" {
+		t.Errorf("unexpected statement %q", q.statement)
+	}
+	if q.parent == nil || q.parent.span.SpanID != "root" {
+		t.Errorf("expected parent root, got %v", q.parent)
+	}
+
+	h := groups[1]
+	if h.operation != "GET /price" || len(h.spans) != 3 {
+		t.Errorf("unexpected GET /price group: %s ×%d", h.operation, len(h.spans))
+	}
+
+	if got := findRepeats(roots, 5, &config{}); len(got) != 0 {
+		t.Errorf("expected no groups with threshold 5, got %d", len(got))
+	}
+}
+
+func TestPrintRepeats(t *testing.T) {
+	roots, _ := buildTree(repeatsTrace())
+
+	var buf bytes.Buffer
+	printRepeats(&buf, roots, &config{repeats: 3})
+
+	want := `db.query [postgres] ×4 under GET /orders [api]
+  total 2.00ms, wall 3.50ms, sequential
+  SELECT * FROM items WHERE id = ?
+GET /price [pricing] ×3 under GET /orders [api]
+  total 600us, wall 200us, parallel (max 3 concurrent)
+`
+	if got := buf.String(); got != want {
+		t.Errorf("printRepeats() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+type errorOrigin struct {
+	node      *spanNode
+	ancestors []*spanNode
+}
+
+// findErrorOrigins returns the error spans matching the filters that have no
+// errors beneath them. These are where errors originated; errors on their
+// ancestors were propagated from them.
+func findErrorOrigins(roots []*spanNode, cfg *config) []errorOrigin {
+	var origins []errorOrigin
+	var walk func(node *spanNode, ancestors []*spanNode) bool
+	walk = func(node *spanNode, ancestors []*spanNode) bool {
+		ancestors = append(ancestors, node)
+		descendantError := false
+		for _, child := range node.children {
+			if walk(child, ancestors) {
+				descendantError = true
+			}
+		}
+		isError := node.hasError()
+		if isError && !descendantError && node.matchesSelf(cfg) {
+			origins = append(origins, errorOrigin{
+				node:      node,
+				ancestors: append([]*spanNode(nil), ancestors[:len(ancestors)-1]...),
+			})
+		}
+		return isError || descendantError
+	}
+	for _, root := range roots {
+		walk(root, nil)
+	}
+	return origins
+}
+
+// errorMessage returns the message recorded with the span's error, if any.
+func (n *spanNode) errorMessage() string {
+	for _, key := range []string{"error.message", "exception.message", "otel.status_description"} {
+		for _, t := range n.span.Tags {
+			if t.Key == key {
+				if s, ok := t.Value.(string); ok && s != "" {
+					return s
+				}
+			}
+		}
+	}
+	return ""
+}
+
+func printRootCause(w io.Writer, roots []*spanNode, startTime int64, cfg *config) {
+	origins := findErrorOrigins(roots, cfg)
+	if len(origins) == 0 {
+		fmt.Fprintln(w, "no error spans")
+		return
+	}
+
+	propagated := make(map[*spanNode]bool)
+	for i, o := range origins {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		n := o.node
+		fmt.Fprintf(w, "origin: %s [%s] +%s %s",
+			n.span.OperationName,
+			n.service,
+			formatDuration(n.span.StartTime-startTime),
+			formatDuration(n.span.Duration),
+		)
+		if msg := n.errorMessage(); msg != "" {
+			fmt.Fprintf(w, ": %s", msg)
+		}
+		fmt.Fprintln(w)
+
+		for j := len(o.ancestors) - 1; j >= 0; j-- {
+			a := o.ancestors[j]
+			fmt.Fprintf(w, "  <- %s [%s]", a.span.OperationName, a.service)
+			if a.hasError() {
+				fmt.Fprint(w, " propagated")
+				propagated[a] = true
+			}
+			fmt.Fprintln(w)
+		}
+	}
+
+	fmt.Fprintf(w, "\n%d originating errors, %d propagated\n", len(origins), len(propagated))
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func rootCauseTrace() trace {
+	errTag := tag{Key: "error", Value: true}
+	return trace{
+		TraceID: "trace1",
+		Spans: []span{
+			{SpanID: "root", OperationName: "GET /checkout", StartTime: 1000, Duration: 10_000, ProcessID: "p1", Tags: []tag{errTag}},
+			{
+				SpanID:        "process",
+				OperationName: "checkout.process",
+				References:    []reference{{RefType: "CHILD_OF", SpanID: "root"}},
+				StartTime:     1100,
+				Duration:      8_000,
+				ProcessID:     "p1",
+			},
+			{
+				SpanID:        "charge",
+				OperationName: "charge",
+				References:    []reference{{RefType: "CHILD_OF", SpanID: "process"}},
+				StartTime:     1200,
+				Duration:      5_000,
+				ProcessID:     "p2",
+				Tags:          []tag{{Key: "otel.status_code", Value: "ERROR"}},
+			},
+			{
+				SpanID:        "query",
+				OperationName: "db.query",
+				References:    []reference{{RefType: "CHILD_OF", SpanID: "charge"}},
+				StartTime:     1300,
+				Duration:      1_000,
+				ProcessID:     "p3",
+				Tags:          []tag{errTag, {Key: "error.message", Value: "connection refused"}},
+			},
+			{
+				SpanID:        "cache",
+				OperationName: "cache.get",
+				References:    []reference{{RefType: "CHILD_OF", SpanID: "root"}},
+				StartTime:     9500,
+				Duration:      100,
+				ProcessID:     "p1",
+				Tags:          []tag{errTag},
+			},
+		},
+		Processes: map[string]process{
+			"p1": {ServiceName: "api"},
+			"p2": {ServiceName: "payments"},
+			"p3": {ServiceName: "postgres"},
+		},
+	}
+}
+
+func TestFindErrorOrigins(t *testing.T) {
+	roots, _ := buildTree(rootCauseTrace())
+
+	origins := findErrorOrigins(roots, &config{})
+	if len(origins) != 2 {
+		t.Fatalf("expected 2 origins, got %d", len(origins))
+	}
+	if origins[0].node.span.SpanID != "query" {
+		t.Errorf("expected first origin 'query', got %s", origins[0].node.span.SpanID)
+	}
+	if len(origins[0].ancestors) != 3 {
+		t.Errorf("expected 3 ancestors for 'query', got %d", len(origins[0].ancestors))
+	}
+	if origins[1].node.span.SpanID != "cache" {
+		t.Errorf("expected second origin 'cache', got %s", origins[1].node.span.SpanID)
+	}
+
+	filtered := findErrorOrigins(roots, &config{service: "postgres"})
+	if len(filtered) != 1 || filtered[0].node.span.SpanID != "query" {
+		t.Errorf("expected only 'query' with service filter, got %d origins", len(filtered))
+	}
+}
+
+func TestSpanNode_errorMessage(t *testing.T) {
+	tests := []struct {
+		name     string
+		tags     []tag
+		expected string
+	}{
+		{name: "no message", tags: []tag{{Key: "error", Value: true}}, expected: ""},
+		{name: "error.message", tags: []tag{{Key: "error.message", Value: "boom"}}, expected: "boom"},
+		{
+			name: "prefers error.message over status description",
+			tags: []tag{
+				{Key: "otel.status_description", Value: "status"},
+				{Key: "error.message", Value: "boom"},
+			},
+			expected: "boom",
+		},
+		{name: "non-string ignored", tags: []tag{{Key: "exception.message", Value: 42}}, expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := &spanNode{span: span{Tags: tt.tags}}
+			if got := n.errorMessage(); got != tt.expected {
+				t.Errorf("errorMessage() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestPrintRootCause(t *testing.T) {
+	roots, startTime := buildTree(rootCauseTrace())
+
+	var buf bytes.Buffer
+	printRootCause(&buf, roots, startTime, &config{})
+
+	want := `origin: db.query [postgres] +300us 1.00ms: connection refused
+  <- charge [payments] propagated
+  <- checkout.process [api]
+  <- GET /checkout [api] propagated
+
+origin: cache.get [api] +8.50ms 100us
+  <- GET /checkout [api] propagated
+
+2 originating errors, 2 propagated
+`
+	if got := buf.String(); got != want {
+		t.Errorf("printRootCause() =\n%s\nwant\n%s", got, want)
+	}
+}
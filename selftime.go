@@ -0,0 +1,90 @@
+package main
+
+import "sort"
+
+// computeSelfTimes sets the self time of every span in the tree.
+func computeSelfTimes(nodes []*spanNode) {
+	for _, node := range nodes {
+		node.self = node.selfTime()
+		computeSelfTimes(node.children)
+	}
+}
+
+// sortTree orders the siblings at every level by duration or self time,
+// longest first.
+func sortTree(nodes []*spanNode, by string) {
+	sort.SliceStable(nodes, func(i, j int) bool {
+		if by == "self" {
+			return nodes[i].self > nodes[j].self
+		}
+		return nodes[i].span.Duration > nodes[j].span.Duration
+	})
+	for _, node := range nodes {
+		sortTree(node.children, by)
+	}
+}
+
+type interval struct{ start, end int64 }
+
+// selfTime returns the time the span spent outside of its children. Children
+// may run in parallel or overlap, so the union of their intervals (clipped to
+// the span itself) is subtracted rather than the sum of their durations.
+func (n *spanNode) selfTime() int64 {
+	var covered int64
+	for _, iv := range n.childIntervals() {
+		covered += iv.end - iv.start
+	}
+	return n.span.Duration - covered
+}
+
+// childIntervals returns the union of the intervals in which at least one
+// child was running, clipped to the span and sorted by start time.
+func (n *spanNode) childIntervals() []interval {
+	start := n.span.StartTime
+	end := start + n.span.Duration
+
+	intervals := make([]interval, 0, len(n.children))
+	for _, child := range n.children {
+		s := max(child.span.StartTime, start)
+		e := min(child.span.StartTime+child.span.Duration, end)
+		if e > s {
+			intervals = append(intervals, interval{s, e})
+		}
+	}
+	sort.Slice(intervals, func(i, j int) bool {
+		return intervals[i].start < intervals[j].start
+	})
+
+	var merged []interval
+	for _, iv := range intervals {
+		if last := len(merged) - 1; last >= 0 && iv.start <= merged[last].end {
+			merged[last].end = max(merged[last].end, iv.end)
+			continue
+		}
+		merged = append(merged, iv)
+	}
+	return merged
+}
+
+// gaps returns the intervals of at least threshold microseconds inside the
+// span during which none of its children were running. Spans without
+// children have no gaps.
+func (n *spanNode) gaps(threshold int64) []interval {
+	if len(n.children) == 0 {
+		return nil
+	}
+
+	var gaps []interval
+	cursor := n.span.StartTime
+	add := func(end int64) {
+		if end-cursor >= threshold && end > cursor {
+			gaps = append(gaps, interval{cursor, end})
+		}
+	}
+	for _, iv := range n.childIntervals() {
+		add(iv.start)
+		cursor = iv.end
+	}
+	add(n.span.StartTime + n.span.Duration)
+	return gaps
+}
@@ -0,0 +1,194 @@
+package main
+
+import "testing"
+
+func TestSpanNode_selfTime(t *testing.T) {
+	child := func(start, duration int64) *spanNode {
+		return &spanNode{span: span{StartTime: start, Duration: duration}}
+	}
+
+	tests := []struct {
+		name     string
+		node     *spanNode
+		expected int64
+	}{
+		{
+			name:     "no children",
+			node:     &spanNode{span: span{StartTime: 1000, Duration: 500}},
+			expected: 500,
+		},
+		{
+			name: "sequential children",
+			node: &spanNode{
+				span:     span{StartTime: 1000, Duration: 1000},
+				children: []*spanNode{child(1100, 200), child(1400, 300)},
+			},
+			expected: 500,
+		},
+		{
+			name: "parallel children",
+			node: &spanNode{
+				span:     span{StartTime: 1000, Duration: 1000},
+				children: []*spanNode{child(1100, 400), child(1100, 400), child(1200, 100)},
+			},
+			expected: 600,
+		},
+		{
+			name: "overlapping children",
+			node: &spanNode{
+				span:     span{StartTime: 1000, Duration: 1000},
+				children: []*spanNode{child(1100, 300), child(1300, 300)},
+			},
+			expected: 500,
+		},
+		{
+			name: "child outlives parent",
+			node: &spanNode{
+				span:     span{StartTime: 1000, Duration: 1000},
+				children: []*spanNode{child(1800, 5000)},
+			},
+			expected: 800,
+		},
+		{
+			name: "child starts before parent",
+			node: &spanNode{
+				span:     span{StartTime: 1000, Duration: 1000},
+				children: []*spanNode{child(500, 1000)},
+			},
+			expected: 500,
+		},
+		{
+			name: "child fully outside parent",
+			node: &spanNode{
+				span:     span{StartTime: 1000, Duration: 1000},
+				children: []*spanNode{child(3000, 1000)},
+			},
+			expected: 1000,
+		},
+		{
+			name: "children cover whole parent",
+			node: &spanNode{
+				span:     span{StartTime: 1000, Duration: 1000},
+				children: []*spanNode{child(900, 600), child(1500, 600)},
+			},
+			expected: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.node.selfTime(); got != tt.expected {
+				t.Errorf("selfTime() = %d, want %d", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestComputeSelfTimes(t *testing.T) {
+	grandchild := &spanNode{span: span{StartTime: 1200, Duration: 100}}
+	child := &spanNode{span: span{StartTime: 1100, Duration: 400}, children: []*spanNode{grandchild}}
+	root := &spanNode{span: span{StartTime: 1000, Duration: 1000}, children: []*spanNode{child}}
+
+	computeSelfTimes([]*spanNode{root})
+
+	if root.self != 600 {
+		t.Errorf("root.self = %d, want 600", root.self)
+	}
+	if child.self != 300 {
+		t.Errorf("child.self = %d, want 300", child.self)
+	}
+	if grandchild.self != 100 {
+		t.Errorf("grandchild.self = %d, want 100", grandchild.self)
+	}
+}
+
+func TestSortTree(t *testing.T) {
+	newNodes := func() []*spanNode {
+		return []*spanNode{
+			{span: span{SpanID: "a", Duration: 100}, self: 90},
+			{span: span{SpanID: "b", Duration: 300}, self: 10},
+			{span: span{SpanID: "c", Duration: 200}, self: 200},
+		}
+	}
+
+	tests := []struct {
+		by   string
+		want []string
+	}{
+		{by: "duration", want: []string{"b", "c", "a"}},
+		{by: "self", want: []string{"c", "a", "b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.by, func(t *testing.T) {
+			nodes := newNodes()
+			sortTree(nodes, tt.by)
+			for i, id := range tt.want {
+				if nodes[i].span.SpanID != id {
+					t.Errorf("sortTree()[%d] = %s, want %s", i, nodes[i].span.SpanID, id)
+				}
+			}
+		})
+	}
+}
+
+func TestSpanNode_gaps(t *testing.T) {
+	child := func(start, duration int64) *spanNode {
+		return &spanNode{span: span{StartTime: start, Duration: duration}}
+	}
+
+	tests := []struct {
+		name      string
+		node      *spanNode
+		threshold int64
+		expected  []interval
+	}{
+		{
+			name:      "no children",
+			node:      &spanNode{span: span{StartTime: 1000, Duration: 1000}},
+			threshold: 1,
+			expected:  nil,
+		},
+		{
+			name: "leading, middle and trailing gaps",
+			node: &spanNode{
+				span:     span{StartTime: 1000, Duration: 1000},
+				children: []*spanNode{child(1200, 100), child(1600, 100)},
+			},
+			threshold: 1,
+			expected:  []interval{{1000, 1200}, {1300, 1600}, {1700, 2000}},
+		},
+		{
+			name: "threshold drops short gaps",
+			node: &spanNode{
+				span:     span{StartTime: 1000, Duration: 1000},
+				children: []*spanNode{child(1050, 100), child(1600, 350)},
+			},
+			threshold: 100,
+			expected:  []interval{{1150, 1600}},
+		},
+		{
+			name: "parallel children leave no gap between them",
+			node: &spanNode{
+				span:     span{StartTime: 1000, Duration: 1000},
+				children: []*spanNode{child(1000, 600), child(1500, 500)},
+			},
+			threshold: 1,
+			expected:  nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.node.gaps(tt.threshold)
+			if len(got) != len(tt.expected) {
+				t.Fatalf("gaps() = %v, want %v", got, tt.expected)
+			}
+			for i := range got {
+				if got[i] != tt.expected[i] {
+					t.Errorf("gaps()[%d] = %v, want %v", i, got[i], tt.expected[i])
+				}
+			}
+		})
+	}
+}
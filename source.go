@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// traceSource loads several traces, either by ID or URL, from a list read from
+// stdin when the only argument is -, or by searching Jaeger.
+type traceSource struct {
+	jaegerURL string
+	service   string
+	operation string
+	limit     int
+	lookback  time.Duration
+}
+
+func (s *traceSource) register(fs *flag.FlagSet) {
+	fs.StringVar(&s.jaegerURL, "url", "http://localhost:16686", "Jaeger URL")
+	fs.StringVar(&s.service, "service", "", "search Jaeger for traces from this service")
+	fs.StringVar(&s.operation, "operation", "", "restrict the search to this operation")
+	fs.IntVar(&s.limit, "limit", 20, "maximum number of traces to search for")
+	fs.DurationVar(&s.lookback, "lookback", time.Hour, "how far back to search")
+}
+
+func (s *traceSource) load(args []string, stdin io.Reader) ([]trace, error) {
+	if len(args) == 1 && args[0] == "-" {
+		args = nil
+		scanner := bufio.NewScanner(stdin)
+		for scanner.Scan() {
+			if line := strings.TrimSpace(scanner.Text()); line != "" {
+				args = append(args, line)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read trace IDs: %w", err)
+		}
+	}
+
+	if len(args) == 0 {
+		if s.service == "" {
+			return nil, fmt.Errorf("no trace IDs given and no -service to search")
+		}
+		return s.search()
+	}
+
+	traces := make([]trace, 0, len(args))
+	for _, input := range args {
+		baseURL, traceID := parseInput(input, s.jaegerURL)
+		t, err := fetchTrace(baseURL, traceID)
+		if err != nil {
+			return nil, err
+		}
+		traces = append(traces, t)
+	}
+	return traces, nil
+}
+
+func (s *traceSource) search() ([]trace, error) {
+	q := url.Values{}
+	q.Set("service", s.service)
+	if s.operation != "" {
+		q.Set("operation", s.operation)
+	}
+	q.Set("limit", strconv.Itoa(s.limit))
+	// The API takes the time range as start and end in microseconds; the
+	// lookback parameter is only understood by the Jaeger UI.
+	end := time.Now()
+	q.Set("start", strconv.FormatInt(end.Add(-s.lookback).UnixMicro(), 10))
+	q.Set("end", strconv.FormatInt(end.UnixMicro(), 10))
+
+	traces, err := getTraces(fmt.Sprintf("%s/api/traces?%s", s.jaegerURL, q.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	if len(traces) == 0 {
+		return nil, fmt.Errorf("no traces found for service %s", s.service)
+	}
+	return traces, nil
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func jaegerServer(t *testing.T, traces map[string]trace) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var resp traceResponse
+		if id, ok := strings.CutPrefix(r.URL.Path, "/api/traces/"); ok {
+			tr, found := traces[id]
+			if !found {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			resp.Data = []trace{tr}
+		} else if r.URL.Path == "/api/traces" {
+			for _, tr := range traces {
+				if tr.Processes["p1"].ServiceName == r.URL.Query().Get("service") {
+					resp.Data = append(resp.Data, tr)
+				}
+			}
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestTraceSource_Load(t *testing.T) {
+	traces := map[string]trace{
+		"t1": {TraceID: "t1", Processes: map[string]process{"p1": {ServiceName: "api"}}},
+		"t2": {TraceID: "t2", Processes: map[string]process{"p1": {ServiceName: "api"}}},
+	}
+	srv := jaegerServer(t, traces)
+
+	tests := []struct {
+		name    string
+		src     traceSource
+		args    []string
+		stdin   string
+		wantIDs []string
+		wantErr bool
+	}{
+		{
+			name:    "trace IDs",
+			src:     traceSource{jaegerURL: srv.URL},
+			args:    []string{"t1", "t2"},
+			wantIDs: []string{"t1", "t2"},
+		},
+		{
+			name:    "trace URL",
+			src:     traceSource{jaegerURL: "http://unused"},
+			args:    []string{srv.URL + "/trace/t2"},
+			wantIDs: []string{"t2"},
+		},
+		{
+			name:    "IDs from stdin",
+			src:     traceSource{jaegerURL: srv.URL},
+			args:    []string{"-"},
+			stdin:   "t2\n\n t1 \n",
+			wantIDs: []string{"t2", "t1"},
+		},
+		{
+			name:    "search",
+			src:     traceSource{jaegerURL: srv.URL, service: "api", limit: 20},
+			wantIDs: []string{"t1", "t2"},
+		},
+		{
+			name:    "search with no results",
+			src:     traceSource{jaegerURL: srv.URL, service: "missing", limit: 20},
+			wantErr: true,
+		},
+		{
+			name:    "missing trace",
+			src:     traceSource{jaegerURL: srv.URL},
+			args:    []string{"t1", "nope"},
+			wantErr: true,
+		},
+		{
+			name:    "nothing to load",
+			src:     traceSource{jaegerURL: srv.URL},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.src.load(tt.args, strings.NewReader(tt.stdin))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			ids := make(map[string]bool)
+			for _, tr := range got {
+				ids[tr.TraceID] = true
+			}
+			if len(got) != len(tt.wantIDs) {
+				t.Fatalf("load() returned %d traces, want %d", len(got), len(tt.wantIDs))
+			}
+			for _, id := range tt.wantIDs {
+				if !ids[id] {
+					t.Errorf("load() missing trace %s", id)
+				}
+			}
+		})
+	}
+}
+
+func TestTraceSource_SearchQuery(t *testing.T) {
+	var query url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		json.NewEncoder(w).Encode(traceResponse{Data: []trace{{TraceID: "t1"}}})
+	}))
+	defer srv.Close()
+
+	src := traceSource{
+		jaegerURL: srv.URL,
+		service:   "api",
+		operation: "GET /checkout",
+		limit:     50,
+		lookback:  30 * time.Minute,
+	}
+	before := time.Now().UnixMicro()
+	if _, err := src.search(); err != nil {
+		t.Fatalf("search() error = %v", err)
+	}
+	after := time.Now().UnixMicro()
+
+	for key, want := range map[string]string{"service": "api", "operation": "GET /checkout", "limit": "50"} {
+		if got := query.Get(key); got != want {
+			t.Errorf("query %s = %q, want %q", key, got, want)
+		}
+	}
+	start, err := strconv.ParseInt(query.Get("start"), 10, 64)
+	if err != nil {
+		t.Fatalf("query start = %q: %v", query.Get("start"), err)
+	}
+	end, err := strconv.ParseInt(query.Get("end"), 10, 64)
+	if err != nil {
+		t.Fatalf("query end = %q: %v", query.Get("end"), err)
+	}
+	if end < before || end > after {
+		t.Errorf("query end = %d, want between %d and %d", end, before, after)
+	}
+	if got := end - start; got != (30 * time.Minute).Microseconds() {
+		t.Errorf("query end - start = %dus, want 30m", got)
+	}
+	if query.Has("lookback") {
+		t.Errorf("query has lookback, which the API ignores")
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+)
+
+type summaryRow struct {
+	service   string
+	operation string
+	count     int
+	total     int64
+	self      int64
+	max       int64
+	errors    int
+}
+
+func (r *summaryRow) add(n *spanNode) {
+	r.count++
+	r.total += n.span.Duration
+	r.self += n.self
+	r.max = max(r.max, n.span.Duration)
+	if n.hasError() {
+		r.errors++
+	}
+}
+
+// buildSummary aggregates the spans matching the filters per service and per
+// service+operation, each sorted by self time, highest first.
+func buildSummary(roots []*spanNode, cfg *config) (services, operations []*summaryRow) {
+	byService := make(map[string]*summaryRow)
+	byOperation := make(map[[2]string]*summaryRow)
+
+	var walk func(nodes []*spanNode)
+	walk = func(nodes []*spanNode) {
+		for _, n := range nodes {
+			if n.matchesSelf(cfg) {
+				s, ok := byService[n.service]
+				if !ok {
+					s = &summaryRow{service: n.service}
+					byService[n.service] = s
+					services = append(services, s)
+				}
+				s.add(n)
+
+				key := [2]string{n.service, n.span.OperationName}
+				o, ok := byOperation[key]
+				if !ok {
+					o = &summaryRow{service: n.service, operation: n.span.OperationName}
+					byOperation[key] = o
+					operations = append(operations, o)
+				}
+				o.add(n)
+			}
+			walk(n.children)
+		}
+	}
+	walk(roots)
+
+	sortRows := func(rows []*summaryRow) {
+		sort.SliceStable(rows, func(i, j int) bool {
+			return rows[i].self > rows[j].self
+		})
+	}
+	sortRows(services)
+	sortRows(operations)
+
+	return services, operations
+}
+
+func printSummary(w io.Writer, roots []*spanNode, startTime int64, cfg *config) {
+	services, operations := buildSummary(roots, cfg)
+	duration := traceDuration(roots, startTime)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	printRows := func(header string, rows []*summaryRow, name func(*summaryRow) string) {
+		fmt.Fprintf(tw, "%s\tSPANS\tTOTAL\tSELF\t%%TRACE\tMAX\tERRORS\n", header)
+		for _, r := range rows {
+			pct := 0.0
+			if duration > 0 {
+				pct = float64(r.self) / float64(duration) * 100
+			}
+			fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%.1f%%\t%s\t%d\n",
+				name(r),
+				r.count,
+				formatDuration(r.total),
+				formatDuration(r.self),
+				pct,
+				formatDuration(r.max),
+				r.errors,
+			)
+		}
+	}
+
+	printRows("SERVICE", services, func(r *summaryRow) string {
+		return r.service
+	})
+	fmt.Fprintln(tw)
+	printRows("OPERATION", operations, func(r *summaryRow) string {
+		return fmt.Sprintf("%s [%s]", r.operation, r.service)
+	})
+	tw.Flush()
+}
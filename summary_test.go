@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func summaryTrace() trace {
+	return trace{
+		TraceID: "trace1",
+		Spans: []span{
+			{SpanID: "root", OperationName: "GET /checkout", StartTime: 1000, Duration: 10_000, ProcessID: "p1"},
+			{
+				SpanID:        "q1",
+				OperationName: "db.query",
+				References:    []reference{{RefType: "CHILD_OF", SpanID: "root"}},
+				StartTime:     2000,
+				Duration:      2_000,
+				ProcessID:     "p2",
+			},
+			{
+				SpanID:        "q2",
+				OperationName: "db.query",
+				References:    []reference{{RefType: "CHILD_OF", SpanID: "root"}},
+				StartTime:     5000,
+				Duration:      3_000,
+				ProcessID:     "p2",
+				Tags:          []tag{{Key: "error", Value: true}},
+			},
+			{
+				SpanID:        "c",
+				OperationName: "cache.get",
+				References:    []reference{{RefType: "CHILD_OF", SpanID: "root"}},
+				StartTime:     9000,
+				Duration:      500,
+				ProcessID:     "p1",
+			},
+		},
+		Processes: map[string]process{
+			"p1": {ServiceName: "api"},
+			"p2": {ServiceName: "postgres"},
+		},
+	}
+}
+
+func TestBuildSummary(t *testing.T) {
+	roots, _ := buildTree(summaryTrace())
+	computeSelfTimes(roots)
+
+	services, operations := buildSummary(roots, &config{})
+
+	if len(services) != 2 {
+		t.Fatalf("expected 2 services, got %d", len(services))
+	}
+	api := services[0]
+	if api.service != "api" || api.count != 2 || api.total != 10_500 || api.self != 5_000 || api.max != 10_000 {
+		t.Errorf("unexpected api row: %+v", *api)
+	}
+	pg := services[1]
+	if pg.service != "postgres" || pg.count != 2 || pg.self != 5_000 || pg.errors != 1 {
+		t.Errorf("unexpected postgres row: %+v", *pg)
+	}
+
+	if len(operations) != 3 {
+		t.Fatalf("expected 3 operations, got %d", len(operations))
+	}
+	q := operations[0]
+	if q.operation != "db.query" || q.count != 2 || q.total != 5_000 || q.max != 3_000 || q.errors != 1 {
+		t.Errorf("unexpected db.query row: %+v", *q)
+	}
+}
+
+func TestBuildSummary_Filtered(t *testing.T) {
+	roots, _ := buildTree(summaryTrace())
+	computeSelfTimes(roots)
+
+	services, operations := buildSummary(roots, &config{errorsOnly: true})
+
+	if len(services) != 1 || services[0].service != "postgres" || services[0].count != 1 {
+		t.Errorf("expected only the erroring postgres span, got %+v", services)
+	}
+	if len(operations) != 1 || operations[0].operation != "db.query" {
+		t.Errorf("expected only db.query, got %+v", operations)
+	}
+}
+
+func TestPrintSummary(t *testing.T) {
+	roots, startTime := buildTree(summaryTrace())
+	computeSelfTimes(roots)
+
+	var buf bytes.Buffer
+	printSummary(&buf, roots, startTime, &config{})
+
+	want := `SERVICE   SPANS  TOTAL    SELF    %TRACE  MAX      ERRORS
+api       2      10.50ms  5.00ms  50.0%   10.00ms  0
+postgres  2      5.00ms   5.00ms  50.0%   3.00ms   1
+
+OPERATION            SPANS  TOTAL    SELF    %TRACE  MAX      ERRORS
+db.query [postgres]  2      5.00ms   5.00ms  50.0%   3.00ms   1
+GET /checkout [api]  1      10.00ms  4.50ms  45.0%   10.00ms  0
+cache.get [api]      1      500us    500us   5.0%    500us    0
+`
+	if got := buf.String(); got != want {
+		t.Errorf("printSummary() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+type rankedSpan struct {
+	node      *spanNode
+	ancestors []*spanNode
+	value     int64
+}
+
+// topSpans returns the n spans matching the filters with the highest duration
+// or self time, depending on by, along with their ancestors.
+func topSpans(roots []*spanNode, n int, by string, cfg *config) []rankedSpan {
+	var ranked []rankedSpan
+	var walk func(node *spanNode, ancestors []*spanNode)
+	walk = func(node *spanNode, ancestors []*spanNode) {
+		if node.matchesSelf(cfg) {
+			value := node.span.Duration
+			if by == "self" {
+				value = node.self
+			}
+			ranked = append(ranked, rankedSpan{
+				node:      node,
+				ancestors: append([]*spanNode(nil), ancestors...),
+				value:     value,
+			})
+		}
+		ancestors = append(ancestors, node)
+		for _, child := range node.children {
+			walk(child, ancestors)
+		}
+	}
+	for _, root := range roots {
+		walk(root, nil)
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].value > ranked[j].value
+	})
+	if len(ranked) > n {
+		ranked = ranked[:n]
+	}
+	return ranked
+}
+
+func printTop(w io.Writer, roots []*spanNode, startTime int64, cfg *config) {
+	for i, r := range topSpans(roots, cfg.top, cfg.topBy, cfg) {
+		offset := r.node.span.StartTime - startTime
+		fmt.Fprintf(w, "%2d. %s %s [%s] +%s",
+			i+1,
+			formatDuration(r.value),
+			r.node.span.OperationName,
+			r.node.service,
+			formatDuration(offset),
+		)
+		if cfg.topBy == "self" {
+			fmt.Fprintf(w, " (total %s)", formatDuration(r.node.span.Duration))
+		}
+		fmt.Fprintln(w)
+
+		if len(r.ancestors) > 0 {
+			path := make([]string, len(r.ancestors))
+			for j, a := range r.ancestors {
+				path[j] = a.span.OperationName
+			}
+			fmt.Fprintf(w, "    %s\n", strings.Join(path, " > "))
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestTopSpans(t *testing.T) {
+	tr := trace{
+		TraceID: "trace1",
+		Spans: []span{
+			{SpanID: "root", OperationName: "root", StartTime: 1000, Duration: 10_000, ProcessID: "p1"},
+			{
+				SpanID:        "a",
+				OperationName: "a",
+				References:    []reference{{RefType: "CHILD_OF", SpanID: "root"}},
+				StartTime:     1000,
+				Duration:      6_000,
+				ProcessID:     "p1",
+			},
+			{
+				SpanID:        "b",
+				OperationName: "b",
+				References:    []reference{{RefType: "CHILD_OF", SpanID: "a"}},
+				StartTime:     2000,
+				Duration:      5_000,
+				ProcessID:     "p2",
+			},
+			{
+				SpanID:        "c",
+				OperationName: "c",
+				References:    []reference{{RefType: "CHILD_OF", SpanID: "root"}},
+				StartTime:     8000,
+				Duration:      500,
+				ProcessID:     "p2",
+			},
+		},
+		Processes: map[string]process{
+			"p1": {ServiceName: "frontend"},
+			"p2": {ServiceName: "backend"},
+		},
+	}
+
+	tests := []struct {
+		name string
+		n    int
+		by   string
+		cfg  *config
+		want []string
+	}{
+		{
+			name: "by duration",
+			n:    2,
+			by:   "duration",
+			cfg:  &config{},
+			want: []string{"root", "a"},
+		},
+		{
+			name: "by self time",
+			n:    3,
+			by:   "self",
+			cfg:  &config{},
+			want: []string{"b", "root", "a"},
+		},
+		{
+			name: "n larger than span count",
+			n:    10,
+			by:   "duration",
+			cfg:  &config{},
+			want: []string{"root", "a", "b", "c"},
+		},
+		{
+			name: "respects filters",
+			n:    10,
+			by:   "duration",
+			cfg:  &config{service: "backend"},
+			want: []string{"b", "c"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			roots, _ := buildTree(tr)
+			computeSelfTimes(roots)
+			got := topSpans(roots, tt.n, tt.by, tt.cfg)
+			if len(got) != len(tt.want) {
+				t.Fatalf("topSpans() returned %d spans, want %d", len(got), len(tt.want))
+			}
+			for i, id := range tt.want {
+				if got[i].node.span.SpanID != id {
+					t.Errorf("topSpans()[%d] = %s, want %s", i, got[i].node.span.SpanID, id)
+				}
+			}
+		})
+	}
+}
+
+func TestPrintTop(t *testing.T) {
+	roots := []*spanNode{{
+		span:    span{OperationName: "root", StartTime: 1000, Duration: 3000},
+		service: "frontend",
+		children: []*spanNode{{
+			span:    span{OperationName: "query", StartTime: 1500, Duration: 2000},
+			service: "db",
+		}},
+	}}
+	computeSelfTimes(roots)
+	cfg := &config{top: 1, topBy: "self"}
+
+	var buf bytes.Buffer
+	printTop(&buf, roots, 1000, cfg)
+
+	want := ` 1. 2.00ms query [db] +500us (total 2.00ms)
+    root
+`
+	if got := buf.String(); got != want {
+		t.Errorf("printTop() =\n%s\nwant\n%s", got, want)
+	}
+}
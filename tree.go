@@ -0,0 +1,44 @@
+package main
+
+import "strings"
+
+// guideSet holds the connectors drawn in front of a span: branch and last
+// lead the span's own line, pipe and space continue beneath it for its
+// children depending on whether more siblings follow.
+type guideSet struct {
+	branch, last, pipe, space string
+}
+
+// guideStyles maps the -guides styles to their connectors.
+var guideStyles = map[string]guideSet{
+	"none":    {branch: "  ", last: "  ", pipe: "  ", space: "  "},
+	"ascii":   {branch: "|- ", last: "`- ", pipe: "|  ", space: "   "},
+	"unicode": {branch: "├─ ", last: "└─ ", pipe: "│  ", space: "   "},
+}
+
+// treeGuides returns the connectors for the configured style, falling back to
+// plain indentation.
+func (cfg *config) treeGuides() guideSet {
+	if g, ok := guideStyles[cfg.guides]; ok {
+		return g
+	}
+	return guideStyles["none"]
+}
+
+// resolveGuides returns the style to draw for -guides, choosing for auto
+// box-drawing characters when the locale uses UTF-8 and ASCII otherwise.
+// As in the C library, LC_ALL overrides LC_CTYPE, which overrides LANG.
+func resolveGuides(style string, getenv func(string) string) string {
+	if style != "auto" {
+		return style
+	}
+	for _, name := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		if v := strings.ToLower(getenv(name)); v != "" {
+			if strings.Contains(v, "utf-8") || strings.Contains(v, "utf8") {
+				return "unicode"
+			}
+			return "ascii"
+		}
+	}
+	return "ascii"
+}
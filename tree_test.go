@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPrintRoots_Guides(t *testing.T) {
+	tr := trace{
+		TraceID: "trace1",
+		Spans: []span{
+			{SpanID: "root", OperationName: "root", StartTime: 1000, Duration: 1000, ProcessID: "p1"},
+			{
+				SpanID: "a", OperationName: "a", StartTime: 1100, Duration: 300, ProcessID: "p1",
+				References: []reference{{RefType: "CHILD_OF", SpanID: "root"}},
+			},
+			{
+				SpanID: "a1", OperationName: "a1", StartTime: 1150, Duration: 100, ProcessID: "p1",
+				References: []reference{{RefType: "CHILD_OF", SpanID: "a"}},
+				Tags:       []tag{{Key: "error", Value: true}},
+			},
+			{
+				SpanID: "b", OperationName: "b", StartTime: 1500, Duration: 200, ProcessID: "p1",
+				References: []reference{{RefType: "CHILD_OF", SpanID: "root"}},
+				Tags:       []tag{{Key: "error", Value: true}},
+			},
+			{
+				SpanID: "c", OperationName: "c", StartTime: 1800, Duration: 100, ProcessID: "p1",
+				References: []reference{{RefType: "CHILD_OF", SpanID: "root"}},
+			},
+		},
+		Processes: map[string]process{
+			"p1": {ServiceName: "svc"},
+		},
+	}
+
+	tests := []struct {
+		name string
+		cfg  *config
+		want string
+	}{
+		{
+			name: "unicode",
+			cfg:  &config{relativeTime: true, guides: "unicode"},
+			want: `root [svc] +0us 1.00ms (self 400us)
+├─ a [svc] +100us 300us (self 200us)
+│  └─ a1 [svc] +150us 100us
+├─ b [svc] +500us 200us
+└─ c [svc] +800us 100us
+`,
+		},
+		{
+			name: "ascii",
+			cfg:  &config{relativeTime: true, guides: "ascii"},
+			want: "root [svc] +0us 1.00ms (self 400us)\n" +
+				"|- a [svc] +100us 300us (self 200us)\n" +
+				"|  `- a1 [svc] +150us 100us\n" +
+				"|- b [svc] +500us 200us\n" +
+				"`- c [svc] +800us 100us\n",
+		},
+		{
+			name: "last visible child closes after filtering",
+			cfg:  &config{relativeTime: true, guides: "unicode", errorsOnly: true},
+			want: `root [svc] +0us 1.00ms (self 400us)
+├─ a [svc] +100us 300us (self 200us)
+│  └─ a1 [svc] +150us 100us
+└─ b [svc] +500us 200us
+`,
+		},
+		{
+			name: "trailing gap closes the level",
+			cfg:  &config{relativeTime: true, guides: "unicode", maxDepth: 2, gaps: 50_000},
+			want: `root [svc] +0us 1.00ms (self 400us)
+├─ (gap 100us)
+├─ a [svc] +100us 300us (self 200us)
+├─ (gap 100us)
+├─ b [svc] +500us 200us
+├─ (gap 100us)
+├─ c [svc] +800us 100us
+└─ (gap 100us)
+`,
+		},
+		{
+			name: "none keeps plain indentation",
+			cfg:  &config{relativeTime: true, guides: "none"},
+			want: `root [svc] +0us 1.00ms (self 400us)
+  a [svc] +100us 300us (self 200us)
+    a1 [svc] +150us 100us
+  b [svc] +500us 200us
+  c [svc] +800us 100us
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			roots, startTime := buildTree(tr)
+			computeSelfTimes(roots)
+			var buf bytes.Buffer
+			printRoots(&buf, roots, startTime, tt.cfg)
+			if got := buf.String(); got != tt.want {
+				t.Errorf("printRoots() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveGuides(t *testing.T) {
+	tests := []struct {
+		name  string
+		style string
+		env   map[string]string
+		want  string
+	}{
+		{name: "explicit style", style: "none", env: map[string]string{"LANG": "en_US.UTF-8"}, want: "none"},
+		{name: "utf-8 LANG", style: "auto", env: map[string]string{"LANG": "en_US.UTF-8"}, want: "unicode"},
+		{name: "utf8 spelling", style: "auto", env: map[string]string{"LANG": "C.utf8"}, want: "unicode"},
+		{name: "non utf-8 locale", style: "auto", env: map[string]string{"LANG": "en_US.ISO-8859-1"}, want: "ascii"},
+		{name: "LC_ALL overrides LANG", style: "auto", env: map[string]string{"LC_ALL": "C", "LANG": "en_US.UTF-8"}, want: "ascii"},
+		{name: "LC_CTYPE overrides LANG", style: "auto", env: map[string]string{"LC_CTYPE": "UTF-8", "LANG": "C"}, want: "unicode"},
+		{name: "no locale", style: "auto", env: nil, want: "ascii"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			getenv := func(name string) string { return tt.env[name] }
+			if got := resolveGuides(tt.style, getenv); got != tt.want {
+				t.Errorf("resolveGuides() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
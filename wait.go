@@ -0,0 +1,48 @@
+package main
+
+import (
+	"errors"
+	"time"
+)
+
+const pollInterval = 500 * time.Millisecond
+
+// waitForTrace fetches the trace, polling for up to timeout while Jaeger
+// does not have it yet or its span count is still changing. Spans can
+// arrive from several services over a few seconds, so the trace is only
+// returned once the count has been stable for quiet. If the timeout passes
+// while spans are still arriving, the latest version is returned.
+//
+// A zero timeout fetches the trace once.
+func waitForTrace(baseURL, traceID string, timeout, quiet, interval time.Duration) (trace, error) {
+	if timeout <= 0 {
+		return fetchTrace(baseURL, traceID)
+	}
+
+	deadline := time.Now().Add(timeout)
+	var latest trace
+	found := false
+	var stableSince time.Time
+
+	for {
+		t, err := fetchTrace(baseURL, traceID)
+		switch {
+		case errors.Is(err, errTraceNotFound):
+		case err != nil:
+			return trace{}, err
+		case !found || len(t.Spans) != len(latest.Spans):
+			latest, found = t, true
+			stableSince = time.Now()
+		case time.Since(stableSince) >= quiet:
+			return t, nil
+		}
+
+		if time.Now().After(deadline) {
+			if found {
+				return latest, nil
+			}
+			return trace{}, err
+		}
+		time.Sleep(interval)
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// sequenceServer serves a trace whose span count follows counts, one entry
+// per request, repeating the last. A count of -1 responds 404 and -2 responds
+// with empty data, as Jaeger can for traces it has not stored yet.
+func sequenceServer(t *testing.T, counts []int) *httptest.Server {
+	t.Helper()
+	var mu sync.Mutex
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		n := counts[min(requests, len(counts)-1)]
+		requests++
+		mu.Unlock()
+
+		switch n {
+		case -1:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		case -2:
+			w.Write([]byte(`{"data":[]}`))
+			return
+		}
+		tr := trace{TraceID: "t1", Spans: make([]span, n)}
+		json.NewEncoder(w).Encode(traceResponse{Data: []trace{tr}})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestWaitForTrace(t *testing.T) {
+	tests := []struct {
+		name      string
+		counts    []int
+		timeout   time.Duration
+		wantSpans int
+		wantErr   error
+	}{
+		{
+			name:      "no wait fetches once",
+			counts:    []int{1, 5},
+			timeout:   0,
+			wantSpans: 1,
+		},
+		{
+			name:    "no wait fails on missing trace",
+			counts:  []int{-1, 5},
+			timeout: 0,
+			wantErr: errTraceNotFound,
+		},
+		{
+			name:      "waits for trace to appear and stabilise",
+			counts:    []int{-1, -1, 2, 4, 5},
+			timeout:   time.Second,
+			wantSpans: 5,
+		},
+		{
+			name:    "not found counts as missing",
+			counts:  []int{-1},
+			timeout: 50 * time.Millisecond,
+			wantErr: errTraceNotFound,
+		},
+		{
+			name:      "waits through empty data",
+			counts:    []int{-2, -2, 3},
+			timeout:   time.Second,
+			wantSpans: 3,
+		},
+		{
+			name:    "empty data counts as missing",
+			counts:  []int{-2},
+			timeout: 50 * time.Millisecond,
+			wantErr: errTraceNotFound,
+		},
+		{
+			name:      "returns latest trace on timeout",
+			counts:    []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20},
+			timeout:   30 * time.Millisecond,
+			wantSpans: -1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := sequenceServer(t, tt.counts)
+			got, err := waitForTrace(srv.URL, "t1", tt.timeout, 20*time.Millisecond, 5*time.Millisecond)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("waitForTrace() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("waitForTrace() error = %v", err)
+			}
+			if tt.wantSpans >= 0 && len(got.Spans) != tt.wantSpans {
+				t.Errorf("waitForTrace() returned %d spans, want %d", len(got.Spans), tt.wantSpans)
+			}
+			if tt.wantSpans < 0 && len(got.Spans) == 0 {
+				t.Error("waitForTrace() returned no spans after timeout")
+			}
+		})
+	}
+}
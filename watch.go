@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+const clearScreen = "\033[H\033[2J"
+
+// watchTrace re-fetches the trace every cfg.watchEvery and re-renders it,
+// marking spans that arrived since the previous render, until ctx is done or
+// no new spans have arrived for cfg.watchIdle. The screen is cleared between
+// renders when clear is set; otherwise renders are separated by a header.
+func watchTrace(
+	ctx context.Context,
+	w io.Writer,
+	fetch func() (trace, error),
+	cfg *config,
+	clear bool,
+) error {
+	seen := make(map[string]bool)
+	lastNew := time.Now()
+	first := true
+
+	for {
+		t, err := fetch()
+		switch {
+		case errors.Is(err, errTraceNotFound):
+		case err != nil:
+			return err
+		default:
+			cfg.newSpans = make(map[string]bool)
+			for _, s := range t.Spans {
+				if !seen[s.SpanID] {
+					seen[s.SpanID] = true
+					if !first {
+						cfg.newSpans[s.SpanID] = true
+					}
+				}
+			}
+			if first || len(cfg.newSpans) > 0 {
+				lastNew = time.Now()
+				if clear {
+					fmt.Fprint(w, clearScreen)
+				} else if !first {
+					fmt.Fprintln(w)
+				}
+				fmt.Fprintf(w, "trace %s: %d spans (+%d new) at %s\n",
+					t.TraceID, len(t.Spans), len(cfg.newSpans), time.Now().Format("15:04:05"))
+				if err := render(w, t, cfg); err != nil {
+					return err
+				}
+				first = false
+			}
+		}
+
+		if cfg.watchIdle > 0 && time.Since(lastNew) >= cfg.watchIdle {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(cfg.watchEvery):
+		}
+	}
+}
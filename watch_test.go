@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWatchTrace(t *testing.T) {
+	root := span{SpanID: "root", OperationName: "call", StartTime: 1000, Duration: 10_000, ProcessID: "p1"}
+	child := span{
+		SpanID:        "child",
+		OperationName: "turn",
+		References:    []reference{{RefType: "CHILD_OF", SpanID: "root"}},
+		StartTime:     2000,
+		Duration:      1_000,
+		ProcessID:     "p1",
+	}
+	processes := map[string]process{"p1": {ServiceName: "svc"}}
+
+	fetches := 0
+	fetch := func() (trace, error) {
+		fetches++
+		switch fetches {
+		case 1:
+			return trace{}, errTraceNotFound
+		case 2, 3:
+			return trace{TraceID: "t1", Spans: []span{root}, Processes: processes}, nil
+		default:
+			return trace{TraceID: "t1", Spans: []span{root, child}, Processes: processes}, nil
+		}
+	}
+
+	cfg := &config{relativeTime: true, watchEvery: time.Millisecond, watchIdle: 20 * time.Millisecond}
+	var buf bytes.Buffer
+	if err := watchTrace(context.Background(), &buf, fetch, cfg, false); err != nil {
+		t.Fatalf("watchTrace() error = %v", err)
+	}
+
+	out := buf.String()
+	renders := strings.Count(out, "trace t1:")
+	if renders != 2 {
+		t.Errorf("expected 2 renders (initial and on new span), got %d:\n%s", renders, out)
+	}
+	if !strings.Contains(out, "trace t1: 2 spans (+1 new)") {
+		t.Errorf("expected header for the new span, got:\n%s", out)
+	}
+	if !strings.Contains(out, "  turn [svc] +1.00ms 1.00ms (new)\n") {
+		t.Errorf("expected new span to be marked, got:\n%s", out)
+	}
+	if strings.Contains(out, "call [svc] +0us 10.00ms (self 9.00ms) (new)") {
+		t.Errorf("expected previously seen span not to be marked, got:\n%s", out)
+	}
+}
+
+func TestWatchTrace_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	fetch := func() (trace, error) {
+		cancel()
+		return trace{TraceID: "t1"}, nil
+	}
+
+	cfg := &config{watchEvery: time.Hour}
+	if err := watchTrace(ctx, &bytes.Buffer{}, fetch, cfg, true); err != nil {
+		t.Fatalf("watchTrace() error = %v", err)
+	}
+}
+
+func TestWatchTrace_FetchError(t *testing.T) {
+	want := errors.New("jaeger returned status 500")
+	fetch := func() (trace, error) { return trace{}, want }
+
+	cfg := &config{watchEvery: time.Millisecond}
+	if err := watchTrace(context.Background(), &bytes.Buffer{}, fetch, cfg, false); !errors.Is(err, want) {
+		t.Fatalf("watchTrace() error = %v, want %v", err, want)
+	}
+}
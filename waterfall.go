@@ -0,0 +1,66 @@
+package main
+
+import (
+	"math"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// waterfallWidth returns the number of columns for the -waterfall bars on a
+// terminal of the given width, leaving the rest for the tree.
+func waterfallWidth(columns int) int {
+	return min(max(columns/3, 10), 60)
+}
+
+// terminalColumns returns the width of the terminal on stdout, or 80 when
+// stdout is not a terminal.
+func terminalColumns() int {
+	if width, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil && width > 0 {
+		return width
+	}
+	return 80
+}
+
+// barCells returns the range of cells, out of width, covered by the interval
+// from start lasting duration within a trace of the given length. Every
+// interval covers at least one cell so that short spans stay visible.
+func barCells(start, duration, traceStart, traceLength int64, width int) (from, to int) {
+	if traceLength <= 0 {
+		return 0, width
+	}
+	scale := float64(width) / float64(traceLength)
+	from = int(float64(start-traceStart) * scale)
+	to = int(math.Ceil(float64(start+duration-traceStart) * scale))
+	from = min(max(from, 0), width-1)
+	to = min(max(to, from+1), width)
+	return from, to
+}
+
+// bar returns the waterfall column for a line, with the interval drawn in
+// fill, or an empty string unless -waterfall is set. A negative duration
+// draws an empty column.
+func (cfg *config) bar(start, duration, traceStart int64, fill, code string) string {
+	if !cfg.waterfall || cfg.jsonOutput {
+		return ""
+	}
+	if duration < 0 {
+		return "|" + strings.Repeat(" ", cfg.barWidth) + "| "
+	}
+	from, to := barCells(start, duration, traceStart, cfg.traceLength, cfg.barWidth)
+	return "|" +
+		strings.Repeat(" ", from) +
+		paint(strings.Repeat(fill, to-from), code, cfg) +
+		strings.Repeat(" ", cfg.barWidth-to) +
+		"| "
+}
+
+// barFill returns the characters drawn for spans and for gaps or collapsed
+// runs, in ASCII when the tree guides are.
+func (cfg *config) barFill() (solid, light string) {
+	if cfg.guides == "ascii" {
+		return "#", "-"
+	}
+	return "█", "░"
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWaterfallWidth(t *testing.T) {
+	tests := []struct {
+		columns, want int
+	}{
+		{columns: 80, want: 26},
+		{columns: 20, want: 10},
+		{columns: 300, want: 60},
+	}
+
+	for _, tt := range tests {
+		if got := waterfallWidth(tt.columns); got != tt.want {
+			t.Errorf("waterfallWidth(%d) = %d, want %d", tt.columns, got, tt.want)
+		}
+	}
+}
+
+func TestBarCells(t *testing.T) {
+	tests := []struct {
+		name             string
+		start, duration  int64
+		wantFrom, wantTo int
+	}{
+		{name: "whole trace", start: 1000, duration: 1000, wantFrom: 0, wantTo: 10},
+		{name: "middle", start: 1200, duration: 300, wantFrom: 2, wantTo: 5},
+		{name: "partial cells round outwards", start: 1250, duration: 100, wantFrom: 2, wantTo: 4},
+		{name: "short span stays visible", start: 1500, duration: 1, wantFrom: 5, wantTo: 6},
+		{name: "zero length at end", start: 2000, duration: 0, wantFrom: 9, wantTo: 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to := barCells(tt.start, tt.duration, 1000, 1000, 10)
+			if from != tt.wantFrom || to != tt.wantTo {
+				t.Errorf("barCells() = %d, %d, want %d, %d", from, to, tt.wantFrom, tt.wantTo)
+			}
+		})
+	}
+}
+
+func TestPrintRoots_Waterfall(t *testing.T) {
+	tr := trace{
+		TraceID: "trace1",
+		Spans: []span{
+			{SpanID: "root", OperationName: "root", StartTime: 1000, Duration: 1000, ProcessID: "p1"},
+			{
+				SpanID: "a", OperationName: "a", StartTime: 1000, Duration: 500, ProcessID: "p1",
+				References: []reference{{RefType: "CHILD_OF", SpanID: "root"}},
+			},
+			{
+				SpanID: "b", OperationName: "b", StartTime: 1500, Duration: 500, ProcessID: "p1",
+				References: []reference{{RefType: "CHILD_OF", SpanID: "root"}},
+			},
+		},
+		Processes: map[string]process{
+			"p1": {ServiceName: "svc"},
+		},
+	}
+
+	tests := []struct {
+		name string
+		cfg  *config
+		want string
+	}{
+		{
+			name: "unicode",
+			cfg:  &config{relativeTime: true, guides: "unicode", waterfall: true, barWidth: 10},
+			want: `|██████████| root [svc] +0us 1.00ms (self 0us)
+|█████     | ├─ a [svc] +0us 500us
+|     █████| └─ b [svc] +500us 500us
+`,
+		},
+		{
+			name: "ascii",
+			cfg:  &config{relativeTime: true, guides: "ascii", waterfall: true, barWidth: 4},
+			want: "|####| root [svc] +0us 1.00ms (self 0us)\n" +
+				"|##  | |- a [svc] +0us 500us\n" +
+				"|  ##| `- b [svc] +500us 500us\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			render(&buf, tr, tt.cfg)
+			if got := buf.String(); got != tt.want {
+				t.Errorf("render() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// timeBound is one end of a time window. It is either an offset from the
+// start of the trace (e.g. +2s) or a wall-clock time, which may omit the date
+// (e.g. 16:43:35.5) in which case the day is the one nearest the trace
+// start, so a trace crossing midnight can be windowed after it.
+type timeBound struct {
+	set       bool
+	relative  bool
+	offset    time.Duration
+	clock     time.Time
+	clockOnly bool
+}
+
+var clockLayouts = []string{"15:04:05.999999", "15:04"}
+
+func (b *timeBound) String() string {
+	switch {
+	case !b.set:
+		return ""
+	case b.relative:
+		return "+" + b.offset.String()
+	case b.clockOnly:
+		return b.clock.Format("15:04:05.999999")
+	default:
+		return b.clock.Format(time.RFC3339Nano)
+	}
+}
+
+func (b *timeBound) Set(s string) error {
+	if strings.HasPrefix(s, "+") {
+		d, err := time.ParseDuration(s[1:])
+		if err != nil {
+			return err
+		}
+		*b = timeBound{set: true, relative: true, offset: d}
+		return nil
+	}
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		*b = timeBound{set: true, clock: t}
+		return nil
+	}
+	for _, layout := range clockLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			*b = timeBound{set: true, clock: t, clockOnly: true}
+			return nil
+		}
+	}
+	return fmt.Errorf("expected +offset (e.g. +2s), clock time (e.g. 16:43:35.5) or RFC3339 time")
+}
+
+// resolve returns the bound in microseconds since the epoch, the unit used by
+// span start times. An unset bound resolves to 0.
+func (b *timeBound) resolve(startTime int64) int64 {
+	switch {
+	case !b.set:
+		return 0
+	case b.relative:
+		return startTime + b.offset.Microseconds()
+	case b.clockOnly:
+		day := time.UnixMicro(startTime)
+		t := time.Date(
+			day.Year(), day.Month(), day.Day(),
+			b.clock.Hour(), b.clock.Minute(), b.clock.Second(), b.clock.Nanosecond(),
+			day.Location(),
+		)
+		// A trace crossing midnight has clock times on the following day,
+		// while -from 16:43 should still cover a trace starting at 16:43:33,
+		// so take the day that puts the time nearest the start.
+		switch {
+		case t.Before(day.Add(-12 * time.Hour)):
+			t = t.AddDate(0, 0, 1)
+		case t.After(day.Add(12 * time.Hour)):
+			t = t.AddDate(0, 0, -1)
+		}
+		return t.UnixMicro()
+	default:
+		return b.clock.UnixMicro()
+	}
+}
+
+// overlapsWindow reports whether the span was running at any point inside
+// the configured window. Zero window ends are unbounded.
+func (n *spanNode) overlapsWindow(cfg *config) bool {
+	if cfg.windowEnd != 0 && n.span.StartTime >= cfg.windowEnd {
+		return false
+	}
+	if cfg.windowStart != 0 && n.span.StartTime+n.span.Duration <= cfg.windowStart {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"io"
+	"testing"
+	"time"
+)
+
+func TestTimeBound_Resolve(t *testing.T) {
+	start := time.Date(2025, 3, 14, 16, 43, 33, 529_000_000, time.Local).UnixMicro()
+
+	tests := []struct {
+		name    string
+		input   string
+		want    int64
+		wantErr bool
+	}{
+		{
+			name:  "relative seconds",
+			input: "+2s",
+			want:  start + 2_000_000,
+		},
+		{
+			name:  "relative fractional",
+			input: "+5.5s",
+			want:  start + 5_500_000,
+		},
+		{
+			name:  "relative milliseconds",
+			input: "+150ms",
+			want:  start + 150_000,
+		},
+		{
+			name:  "clock time with millis",
+			input: "16:43:35.500",
+			want:  time.Date(2025, 3, 14, 16, 43, 35, 500_000_000, time.Local).UnixMicro(),
+		},
+		{
+			name:  "clock time without fraction",
+			input: "16:44:00",
+			want:  time.Date(2025, 3, 14, 16, 44, 0, 0, time.Local).UnixMicro(),
+		},
+		{
+			name:  "clock time hours and minutes",
+			input: "16:45",
+			want:  time.Date(2025, 3, 14, 16, 45, 0, 0, time.Local).UnixMicro(),
+		},
+		{
+			name:  "RFC3339",
+			input: "2025-03-14T16:43:40Z",
+			want:  time.Date(2025, 3, 14, 16, 43, 40, 0, time.UTC).UnixMicro(),
+		},
+		{
+			name:    "bare duration is rejected",
+			input:   "2s",
+			wantErr: true,
+		},
+		{
+			name:    "invalid offset",
+			input:   "+soon",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b timeBound
+			err := b.Set(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Set(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := b.resolve(start); got != tt.want {
+				t.Errorf("resolve() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTimeBound_ResolveAcrossMidnight(t *testing.T) {
+	start := time.Date(2025, 3, 14, 23, 59, 50, 0, time.Local).UnixMicro()
+
+	tests := []struct {
+		input string
+		want  time.Time
+	}{
+		{input: "00:00:05", want: time.Date(2025, 3, 15, 0, 0, 5, 0, time.Local)},
+		{input: "23:59:55", want: time.Date(2025, 3, 14, 23, 59, 55, 0, time.Local)},
+		{input: "23:59", want: time.Date(2025, 3, 14, 23, 59, 0, 0, time.Local)},
+	}
+
+	for _, tt := range tests {
+		var b timeBound
+		if err := b.Set(tt.input); err != nil {
+			t.Fatalf("Set(%q) error = %v", tt.input, err)
+		}
+		if got := b.resolve(start); got != tt.want.UnixMicro() {
+			t.Errorf("resolve(%q) = %v, want %v", tt.input, time.UnixMicro(got), tt.want)
+		}
+	}
+
+	// A trace starting just after midnight, windowed from before it.
+	var b timeBound
+	b.Set("23:59:55")
+	after := time.Date(2025, 3, 15, 0, 0, 5, 0, time.Local)
+	if got, want := b.resolve(after.UnixMicro()), after.Add(-10*time.Second); got != want.UnixMicro() {
+		t.Errorf("resolve() = %v, want %v", time.UnixMicro(got), want)
+	}
+}
+
+func TestRender_EmptyWindow(t *testing.T) {
+	tr := trace{Spans: []span{{SpanID: "root", OperationName: "root", StartTime: 1000, Duration: 1000}}}
+	cfg := &config{}
+	cfg.from.Set("+2s")
+	cfg.to.Set("+1s")
+	if err := render(io.Discard, tr, cfg); err == nil {
+		t.Error("render() error = nil, want error for -from after -to")
+	}
+
+	cfg.to.Set("+3s")
+	if err := render(io.Discard, tr, cfg); err != nil {
+		t.Errorf("render() error = %v", err)
+	}
+}
+
+func TestTimeBound_ResolveUnset(t *testing.T) {
+	var b timeBound
+	if got := b.resolve(1000); got != 0 {
+		t.Errorf("resolve() = %d, want 0", got)
+	}
+}
+
+func TestSpanNode_overlapsWindow(t *testing.T) {
+	node := &spanNode{span: span{StartTime: 2000, Duration: 1000}}
+
+	tests := []struct {
+		name        string
+		windowStart int64
+		windowEnd   int64
+		want        bool
+	}{
+		{name: "no window", want: true},
+		{name: "window contains span", windowStart: 1000, windowEnd: 4000, want: true},
+		{name: "window inside span", windowStart: 2200, windowEnd: 2500, want: true},
+		{name: "span ends at window start", windowStart: 3000, want: false},
+		{name: "span starts at window end", windowEnd: 2000, want: false},
+		{name: "window before span", windowStart: 100, windowEnd: 1500, want: false},
+		{name: "open start overlaps", windowEnd: 2500, want: true},
+		{name: "open end overlaps", windowStart: 2500, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config{windowStart: tt.windowStart, windowEnd: tt.windowEnd}
+			if got := node.overlapsWindow(cfg); got != tt.want {
+				t.Errorf("overlapsWindow() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}